@@ -2,20 +2,28 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/admin"
 	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
 	"github.com/rohankarn35/rate_limiter_golang/internal/server"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/cluster"
 	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/events"
 	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
 	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
@@ -25,30 +33,163 @@ func main() {
 
 	cfg, err := loadConfig()
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		fatal("failed to load config", err)
 	}
+	logger, err := server.NewLogger(cfg.Logging, os.Stderr)
+	if err != nil {
+		fatal("invalid logging configuration", err)
+	}
+	slog.SetDefault(logger)
+
+	stateEncoding, err := limiter.ParseStateEncoding(cfg.Storage.StateEncoding)
+	if err != nil {
+		fatal("invalid storage.state_encoding", err)
+	}
+
+	if cfg.Tracing.Enabled {
+		shutdownTracing, err := server.SetupTracing(ctx, cfg.Tracing)
+		if err != nil {
+			fatal("failed to initialize tracing", err)
+		}
+		defer func() {
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := shutdownTracing(flushCtx); err != nil {
+				slog.Error("tracing shutdown error", "error", err)
+			}
+		}()
+		slog.Info("tracing enabled", "exporter", cfg.Tracing.Exporter)
+	}
+
+	metrics := server.NewMetrics()
 
-	store, closer, err := buildStorage(cfg.Storage)
+	store, closer, err := buildStorage(cfg.Storage, metrics)
 	if err != nil {
-		log.Fatalf("failed to initialize storage: %v", err)
+		fatal("failed to initialize storage", err)
 	}
 	if closer != nil {
 		defer closer()
 	}
 
-	manager, err := limiter.NewManagerFromConfig(cfg.Policies, store)
+	metrics.SetConfigInfo(cfg.Hash(), strings.ToLower(cfg.Storage.Driver))
+	instrumented := storage.NewInstrumentedStorage(store, strings.ToLower(cfg.Storage.Driver), metrics)
+	manager, err := limiter.NewManagerFromConfig(cfg.Policies, instrumented, limiter.WithStateEncoding(stateEncoding))
 	if err != nil {
-		log.Fatalf("failed to build limiter manager: %v", err)
+		fatal("failed to build limiter manager", err)
 	}
+	manager.SetKeyHasher(limiter.NewKeyHasher([]byte(cfg.Logging.KeyHashSecret)))
+	if cfg.Logging.Decisions.Enabled {
+		manager.SetDecisionLogger(limiter.NewDecisionLogger(logger,
+			cfg.Logging.Decisions.AllowedSampleRate, cfg.Logging.Decisions.DeniedSampleRate))
+	}
+	// The snapshot is written once the manager is closed, so admissions still
+	// held by local caches are flushed into it first.
+	var saveSnapshot func()
+	defer func() {
+		if err := manager.Close(); err != nil {
+			slog.Error("limiter shutdown error", "error", err)
+		}
+		if saveSnapshot != nil {
+			saveSnapshot()
+		}
+	}()
 
-	metrics := server.NewMetrics()
-	httpServer := bootstrapHTTPServer(cfg, manager, metrics)
-	grpcServer := bootstrapGRPCServer(cfg, manager, metrics)
+	if memStore, ok := store.(*storage.MemoryStorage); ok && cfg.Storage.Memory.SnapshotPath != "" {
+		path := cfg.Storage.Memory.SnapshotPath
+		n, err := storage.LoadSnapshotFile(memStore, path, manager.OwnsStateKey)
+		if err != nil {
+			slog.Error("failed to restore snapshot", "path", path, "error", err)
+		} else if n > 0 {
+			slog.Info("restored snapshot", "path", path, "keys", n)
+		}
+		saveSnapshot = func() {
+			n, err := storage.SaveSnapshotFile(memStore, path)
+			if err != nil {
+				slog.Error("failed to write snapshot", "path", path, "error", err)
+				return
+			}
+			slog.Info("wrote snapshot", "path", path, "keys", n)
+		}
+	}
+
+	certs, err := buildServerTLS(cfg.Server.TLS, cfg.Cluster)
+	if err != nil {
+		fatal("invalid server.tls configuration", err)
+	}
+	if certs != nil {
+		go certs.Watch(ctx, cfg.Server.TLS.ReloadInterval.Duration())
+	}
+
+	node, clusterServer, err := bootstrapCluster(cfg.Cluster, manager, certs)
+	if err != nil {
+		fatal("failed to join cluster", err)
+	}
+	if node != nil {
+		defer node.Close()
+	}
+
+	bus, stream, err := buildEvents(cfg.Events, manager)
+	if err != nil {
+		fatal("invalid events configuration", err)
+	}
+	if bus != nil {
+		metrics.RegisterEventBus(bus)
+		defer func() {
+			drainCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bus.Close(drainCtx); err != nil {
+				slog.Warn("event sinks did not drain", "error", err)
+			}
+		}()
+	}
+
+	adminHandler, err := buildAdmin(cfg, store, manager, stream)
+	if err != nil {
+		fatal("failed to build admin api", err)
+	}
+	if stream != nil && adminHandler == nil {
+		slog.Warn("events.stream requires the admin api; GET /admin/events is not served")
+	}
+
+	headerOpts, err := middleware.HeaderOptions(cfg.Server.RateLimitHeaders, cfg.Policies)
+	if err != nil {
+		fatal("invalid header configuration", err)
+	}
+	responseOpts, err := middleware.ResponseOptions(cfg.Server.Responses, cfg.Policies)
+	if err != nil {
+		fatal("invalid response configuration", err)
+	}
+	middlewareOpts := append(headerOpts, responseOpts...)
+
+	manager.SetErrorObserver(metrics)
+	manager.SetDecisionObserver(metrics)
+	for _, policy := range manager.Policies() {
+		metrics.SetPolicyInfo(policy.Name, string(policy.Algorithm), string(policy.Mode))
+	}
+	if cfg.Metrics.HeavyHitters.Enabled {
+		manager.EnableHeavyHitters(cfg.Metrics.HeavyHitters.Capacity, cfg.Metrics.HeavyHitters.HalfLife.Duration())
+		metrics.RegisterHeavyHitters(manager, cfg.Metrics.HeavyHitters.ExportTop)
+	}
+	if cfg.Metrics.Enabled && len(cfg.Metrics.WatchedKeys) > 0 {
+		go trackWatchedKeys(ctx, manager, metrics, cfg.Metrics.WatchedKeys, cfg.Metrics.WatchInterval.Duration())
+	}
+	go manager.WatchOverrides(ctx, cfg.Overrides.RefreshInterval.Duration())
+	if cfg.Metrics.Enabled && cfg.Metrics.ActiveKeysInterval > 0 {
+		go trackActiveKeys(ctx, manager, metrics, cfg.Metrics.ActiveKeysInterval.Duration())
+	}
+
+	readiness := server.NewReadiness(store, cfg.Server.Readiness.Timeout.Duration())
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	httpServer := bootstrapHTTPServer(cfg, manager, metrics, readiness, certs, adminHandler, middlewareOpts)
+	grpcServer := bootstrapGRPCServer(cfg, manager, metrics, healthServer, certs, middlewareOpts)
+	readiness.MarkConfigLoaded()
+	go readiness.Watch(ctx, healthServer, cfg.Server.Readiness.Interval.Duration())
 
-	errCh := make(chan error, 2)
+	errCh := make(chan error, 3)
 
 	go func() {
-		log.Printf("HTTP server listening on %s", cfg.Server.Address)
+		slog.Info("HTTP server listening", "address", cfg.Server.Address, "tls", certs != nil)
 		if err := httpServer.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			errCh <- err
 		}
@@ -58,27 +199,56 @@ func main() {
 		if grpcServer == nil {
 			return
 		}
-		log.Printf("gRPC server listening on %s", cfg.Server.GRPCAddress())
+		slog.Info("gRPC server listening", "address", cfg.Server.GRPCAddress(), "tls", certs != nil)
 		if err := grpcServer.Start(); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
 			errCh <- err
 		}
 	}()
 
+	go func() {
+		if clusterServer == nil {
+			return
+		}
+		slog.Info("cluster peer server listening", "address", cfg.Cluster.ListenAddress)
+		if err := clusterServer.Start(); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			errCh <- err
+		}
+	}()
+
 	select {
 	case <-ctx.Done():
-		log.Println("shutting down servers...")
+		slog.Info("shutting down servers")
 	case err := <-errCh:
-		log.Printf("server error: %v", err)
+		slog.Error("server error", "error", err)
+	}
+
+	readiness.Drain()
+	healthServer.Shutdown()
+	if delay := cfg.Server.Readiness.DrainDelay.Duration(); delay > 0 {
+		slog.Info("draining before shutdown", "delay", delay.String())
+		time.Sleep(delay)
 	}
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	if stream != nil {
+		stream.Close()
+	}
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
-		log.Printf("http shutdown error: %v", err)
+		slog.Error("http shutdown error", "error", err)
 	}
 	if grpcServer != nil {
 		grpcServer.Stop(shutdownCtx)
 	}
+	if clusterServer != nil {
+		clusterServer.Stop(shutdownCtx)
+	}
+}
+
+// fatal logs err and exits, like log.Fatalf.
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
 
 func loadConfig() (*config.Config, error) {
@@ -89,47 +259,360 @@ func loadConfig() (*config.Config, error) {
 	return config.Load(path)
 }
 
-func buildStorage(cfg config.StorageConfig) (storage.Storage, func(), error) {
+func buildStorage(cfg config.StorageConfig, metrics *server.Metrics) (storage.Storage, func(), error) {
 	switch strings.ToLower(cfg.Driver) {
 	case "redis":
-		if cfg.Redis.Address == "" {
-			return nil, nil, errors.New("redis address is required")
-		}
-		store := storage.NewRedisStorage(storage.RedisConfig{
-			Addr:     cfg.Redis.Address,
-			Username: cfg.Redis.Username,
-			Password: cfg.Redis.Password,
-			DB:       cfg.Redis.DB,
+		tlsConfig, err := redisTLSConfig(cfg.Redis.TLS)
+		if err != nil {
+			return nil, nil, err
+		}
+		redisCfg := storage.RedisConfig{
+			Mode:             cfg.Redis.Mode,
+			Addr:             cfg.Redis.Address,
+			Addrs:            cfg.Redis.Addresses,
+			MasterName:       cfg.Redis.MasterName,
+			Username:         cfg.Redis.Username,
+			Password:         cfg.Redis.Password,
+			DB:               cfg.Redis.DB,
+			SentinelUsername: cfg.Redis.SentinelUsername,
+			SentinelPassword: cfg.Redis.SentinelPassword,
+			PoolSize:         cfg.Redis.PoolSize,
+			MinIdleConns:     cfg.Redis.MinIdleConns,
+			DialTimeout:      cfg.Redis.DialTimeout.Duration(),
+			ReadTimeout:      cfg.Redis.ReadTimeout.Duration(),
+			WriteTimeout:     cfg.Redis.WriteTimeout.Duration(),
+			TLSConfig:        tlsConfig,
+		}
+		if err := redisCfg.Validate(); err != nil {
+			return nil, nil, err
+		}
+		redisStore := storage.NewRedisStorage(redisCfg)
+		closer := func() {
+			_ = redisStore.Close()
+		}
+		if !cfg.Resilience.Enabled {
+			return redisStore, closer, nil
+		}
+		resync, err := storage.ParseResyncPolicy(cfg.Resilience.Resync)
+		if err != nil {
+			closer()
+			return nil, nil, err
+		}
+		store := storage.NewResilientStorage(redisStore, storage.ResilientConfig{
+			Timeout:            cfg.Resilience.Timeout.Duration(),
+			FailureThreshold:   cfg.Resilience.FailureThreshold,
+			OpenTimeout:        cfg.Resilience.OpenTimeout.Duration(),
+			Resync:             resync,
+			FallbackMaxEntries: cfg.Resilience.FallbackMaxEntries,
+			OnStateChange: func(from, to storage.CircuitState) {
+				slog.Warn("storage circuit changed", "from", from.String(), "to", to.String())
+				metrics.ObserveCircuitState(from, to)
+			},
+		})
+		return store, func() { _ = store.Close() }, nil
+	case "file":
+		memory, err := memoryConfig(cfg.Memory)
+		if err != nil {
+			return nil, nil, err
+		}
+		store, err := storage.NewFileStorage(storage.FileConfig{
+			Path:            cfg.File.Path,
+			SyncInterval:    cfg.File.SyncInterval.Duration(),
+			CompactInterval: cfg.File.CompactInterval.Duration(),
+			Memory:          memory,
 		})
+		if err != nil {
+			return nil, nil, err
+		}
+		metrics.RegisterMemoryStorage(store.Memory())
 		return store, func() {
-			_ = store.Close()
+			if err := store.Close(); err != nil {
+				slog.Error("file storage close error", "error", err)
+			}
 		}, nil
 	default:
-		return storage.NewMemoryStorage(), nil, nil
+		memory, err := memoryConfig(cfg.Memory)
+		if err != nil {
+			return nil, nil, err
+		}
+		store := storage.NewMemoryStorageWithConfig(memory)
+		metrics.RegisterMemoryStorage(store)
+		return store, func() {
+			_ = store.Close()
+		}, nil
 	}
 }
 
-func bootstrapHTTPServer(cfg *config.Config, manager *limiter.Manager, metrics *server.Metrics) *server.HTTPServer {
+// memoryConfig converts storage.memory for the memory and file drivers.
+func memoryConfig(cfg config.MemoryConfig) (storage.MemoryConfig, error) {
+	eviction, err := storage.ParseEvictionPolicy(cfg.Eviction)
+	if err != nil {
+		return storage.MemoryConfig{}, err
+	}
+	return storage.MemoryConfig{
+		SweepInterval: cfg.SweepInterval.Duration(),
+		MaxEntries:    cfg.MaxEntries,
+		Eviction:      eviction,
+		Shards:        cfg.Shards,
+	}, nil
+}
+
+// peerHosts strips the ports from peer addresses, leaving the names their
+// certificates must be valid for.
+func peerHosts(peers []string) []string {
+	hosts := make([]string, 0, len(peers))
+	for _, addr := range peers {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// bootstrapCluster routes every policy through the peer ring and returns the
+// server answering forwarded calls from other replicas.
+func bootstrapCluster(cfg config.ClusterConfig, manager *limiter.Manager, certs *server.CertReloader) (*cluster.Node, *server.GRPCServer, error) {
+	if !cfg.Enabled {
+		return nil, nil, nil
+	}
+	var serverTLS, clientTLS *tls.Config
+	if certs != nil && !cfg.Insecure && len(cfg.Peers) > 1 {
+		var err error
+		if serverTLS, clientTLS, err = certs.PeerConfigs(peerHosts(cfg.Peers)); err != nil {
+			return nil, nil, err
+		}
+	}
+	node, err := cluster.NewNode(cluster.Config{
+		Self:           cfg.AdvertiseAddress,
+		Peers:          cfg.Peers,
+		BatchWindow:    cfg.BatchWindow.Duration(),
+		MaxBatch:       cfg.MaxBatch,
+		RequestTimeout: cfg.RequestTimeout.Duration(),
+		TLS:            clientTLS,
+		Insecure:       cfg.Insecure,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	manager.WrapLimiters(node.Wrap)
+
+	var opts []grpc.ServerOption
+	if serverTLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(serverTLS)))
+	}
+	s := grpc.NewServer(opts...)
+	node.Register(s)
+	return node, server.NewGRPCServer(cfg.ListenAddress, s), nil
+}
+
+// trackActiveKeys periodically counts the keys holding state for every policy.
+func trackActiveKeys(ctx context.Context, manager *limiter.Manager, metrics *server.Metrics, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, policy := range manager.Policies() {
+			n, err := manager.ActiveKeys(ctx, policy.Name)
+			if err != nil {
+				if !errors.Is(err, limiter.ErrNotInspectable) && ctx.Err() == nil {
+					slog.Warn("failed to count active keys", "policy", policy.Name, "error", err)
+				}
+				continue
+			}
+			metrics.ObserveActiveKeys(policy.Name, n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// trackWatchedKeys periodically exports the remaining quota of watched keys
+// without consuming any.
+func trackWatchedKeys(ctx context.Context, manager *limiter.Manager, metrics *server.Metrics, keys []config.WatchedKey, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, watched := range keys {
+			state, err := manager.InspectKey(ctx, watched.Policy, watched.Key)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Warn("failed to inspect watched key", "policy", watched.Policy, "key", watched.Key, "error", err)
+				}
+				continue
+			}
+			metrics.ObserveWatchedKey(watched.Policy, watched.Key, state.Remaining)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildEvents attaches the configured sinks to a new event bus and hands it to
+// manager. The stream is nil unless events.stream is set.
+func buildEvents(cfg config.EventsConfig, manager *limiter.Manager) (*events.Bus, *events.Stream, error) {
+	if !cfg.Enabled {
+		return nil, nil, nil
+	}
+	for _, threshold := range cfg.Thresholds {
+		if threshold <= 0 || threshold > 1 {
+			return nil, nil, fmt.Errorf("threshold %v must be in (0, 1]", threshold)
+		}
+	}
+	bus := events.NewBus()
+	for _, hook := range cfg.Webhooks {
+		if hook.URL == "" {
+			return nil, nil, errors.New("webhook url is required")
+		}
+		types := make([]events.Type, 0, len(hook.Types))
+		for _, name := range hook.Types {
+			t, err := events.ParseType(name)
+			if err != nil {
+				return nil, nil, fmt.Errorf("webhook %s: %w", hook.URL, err)
+			}
+			types = append(types, t)
+		}
+		bus.Attach(events.NewWebhook(events.WebhookConfig{
+			URL:           hook.URL,
+			Headers:       hook.Headers,
+			BatchSize:     hook.BatchSize,
+			FlushInterval: hook.FlushInterval.Duration(),
+			MaxRetries:    hook.MaxRetries,
+			RetryBackoff:  hook.RetryBackoff.Duration(),
+			Client:        &http.Client{Timeout: hook.Timeout.Duration()},
+		}), cfg.Buffer, types...)
+	}
+	var stream *events.Stream
+	if cfg.Stream {
+		stream = events.NewStream(cfg.Buffer)
+		bus.Attach(stream, cfg.Buffer)
+	}
+	manager.SetEventPublisher(bus, cfg.Thresholds)
+	return bus, stream, nil
+}
+
+func buildAdmin(cfg *config.Config, store storage.Storage, manager *limiter.Manager, stream *events.Stream) (http.Handler, error) {
+	if !cfg.Admin.Enabled {
+		return nil, nil
+	}
+	if cfg.Admin.Token == "" {
+		return nil, errors.New("admin.token (or ADMIN_TOKEN) is required when the admin api is enabled")
+	}
+	handler := admin.NewHandler(cfg.Admin.Token)
+	handler.HandleKeys(manager)
+	handler.HandleOverrides(manager)
+	handler.HandleBans(manager)
+	handler.HandleHeavyHitters(manager)
+	if stream != nil {
+		handler.HandleEvents(stream)
+	}
+	if memStore, ok := store.(*storage.MemoryStorage); ok {
+		handler.HandleSnapshots(memStore, cfg.Storage.Memory.SnapshotPath, cfg.Storage.Memory.SnapshotMaxBytes, manager.OwnsStateKey)
+	}
+	return handler, nil
+}
+
+// buildServerTLS loads the listener certificate, and the peer CA when the
+// cluster authenticates peers; nil when TLS is disabled.
+func buildServerTLS(cfg config.ServerTLSConfig, clusterCfg config.ClusterConfig) (*server.CertReloader, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	minVersion, err := server.ParseTLSVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, err := server.ParseClientAuth(cfg.ClientAuth, cfg.ClientCAFile != "")
+	if err != nil {
+		return nil, err
+	}
+	peerCAFile := ""
+	if clusterCfg.Enabled && !clusterCfg.Insecure {
+		peerCAFile = clusterCfg.PeerCAFile
+	}
+	return server.NewCertReloader(server.TLSConfig{
+		CertFile:     cfg.CertFile,
+		KeyFile:      cfg.KeyFile,
+		ClientCAFile: cfg.ClientCAFile,
+		ClientAuth:   clientAuth,
+		MinVersion:   minVersion,
+		PeerCAFile:   peerCAFile,
+	})
+}
+
+// routeClientCerts reports whether client certificates are enforced per
+// route: a client CA with the default client_auth verifies certificates in the
+// handshake when given, leaving probes, metrics and gRPC health open.
+func routeClientCerts(cfg config.ServerTLSConfig) bool {
+	return cfg.Enabled && cfg.ClientCAFile != "" && cfg.ClientAuth == ""
+}
+
+func redisTLSConfig(cfg config.RedisTLSConfig) (*tls.Config, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         cfg.ServerName,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("redis tls ca_file: no certificates found")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func bootstrapHTTPServer(cfg *config.Config, manager *limiter.Manager, metrics *server.Metrics, readiness *server.Readiness, certs *server.CertReloader, adminHandler http.Handler, middlewareOpts []middleware.Option) *server.HTTPServer {
 	apiMux := http.NewServeMux()
 	apiMux.HandleFunc("/api/v1/payments", jsonResponder(map[string]any{"status": "ok"}))
 	apiMux.HandleFunc("/api/v1/premium/resource", jsonResponder(map[string]any{"tier": "premium"}))
 
+	// Probes and scrapes come without client certificates; the routes
+	// serving clients and operators still require one.
+	protect := func(h http.Handler) http.Handler { return h }
+	if routeClientCerts(cfg.Server.TLS) {
+		protect = middleware.RequireClientCert
+	}
+
 	mainMux := http.NewServeMux()
-	mainMux.Handle("/api/", middleware.RateLimiter(manager, metrics)(
+	mainMux.Handle("/api/", protect(middleware.RateLimiter(manager, metrics, middlewareOpts...)(
 		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			apiMux.ServeHTTP(w, r)
 		}),
-	))
+	)))
 
-	mainMux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
+	mainMux.HandleFunc("/healthz", livenessHandler)
+	mainMux.Handle("/readyz", readiness.Handler())
 
 	if cfg.Metrics.Enabled {
 		mainMux.Handle(cfg.Metrics.Path, metrics.Handler())
 	}
 
+	if adminHandler != nil {
+		mainMux.Handle("/admin/", protect(adminHandler))
+	}
+
 	mainMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]string{
@@ -143,25 +626,43 @@ func bootstrapHTTPServer(cfg *config.Config, manager *limiter.Manager, metrics *
 		WriteTimeout: cfg.Server.WriteTimeout.Duration(),
 		IdleTimeout:  cfg.Server.IdleTimeout.Duration(),
 	}
+	if certs != nil {
+		httpCfg.TLS = certs.ServerConfig("h2", "http/1.1")
+	}
 
 	return server.NewHTTPServer(httpCfg, mainMux)
 }
 
-func bootstrapGRPCServer(cfg *config.Config, manager *limiter.Manager, metrics *server.Metrics) *server.GRPCServer {
+func bootstrapGRPCServer(cfg *config.Config, manager *limiter.Manager, metrics *server.Metrics, healthServer *health.Server, certs *server.CertReloader, middlewareOpts []middleware.Option) *server.GRPCServer {
 	address := cfg.Server.GRPCAddress()
 	if address == "" {
 		return nil
 	}
 
+	interceptors := []grpc.UnaryServerInterceptor{
+		middleware.UnaryRateLimitInterceptor(manager, metrics, middlewareOpts...),
+	}
+	if routeClientCerts(cfg.Server.TLS) {
+		interceptors = append([]grpc.UnaryServerInterceptor{middleware.UnaryRequireClientCertInterceptor()}, interceptors...)
+	}
 	opts := []grpc.ServerOption{
-		grpc.UnaryInterceptor(middleware.UnaryRateLimitInterceptor(manager, metrics)),
+		grpc.ChainUnaryInterceptor(interceptors...),
+	}
+	if certs != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(certs.ServerConfig("h2"))))
 	}
 	s := grpc.NewServer(opts...)
-	healthServer := health.NewServer()
 	healthpb.RegisterHealthServer(s, healthServer)
 	return server.NewGRPCServer(address, s)
 }
 
+// livenessHandler reports that the process is up. Dependencies are checked
+// by /readyz, so a broken storage connection never restarts the pod.
+func livenessHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
 func jsonResponder(payload any) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
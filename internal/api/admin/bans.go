@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+)
+
+type banView struct {
+	Policy  string    `json:"policy"`
+	Key     string    `json:"key"`
+	Until   time.Time `json:"until"`
+	Strikes int       `json:"strikes"`
+}
+
+// HandleBans registers penalty box endpoints:
+//
+//	GET    /admin/bans                 list banned keys
+//	DELETE /admin/bans/{policy}/{key}  lift a ban and forget the key's violations
+func (h *Handler) HandleBans(manager *limiter.Manager) {
+	h.mux.HandleFunc("GET /admin/bans", func(w http.ResponseWriter, r *http.Request) {
+		bans, err := manager.Bans(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		views := make([]banView, 0, len(bans))
+		for _, ban := range bans {
+			views = append(views, banView{
+				Policy:  ban.Policy,
+				Key:     ban.Key,
+				Until:   ban.Until.UTC(),
+				Strikes: ban.Strikes,
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"bans": views})
+	})
+
+	h.mux.HandleFunc("DELETE /admin/bans/{policy}/{key}", func(w http.ResponseWriter, r *http.Request) {
+		policy, key := r.PathValue("policy"), r.PathValue("key")
+		if err := manager.ClearBan(r.Context(), policy, key); err != nil {
+			writeLimiterError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"policy": policy, "key": key, "cleared": true})
+	})
+}
@@ -0,0 +1,12 @@
+package admin
+
+import (
+	"github.com/rohankarn35/rate_limiter_golang/pkg/events"
+)
+
+// HandleEvents registers the live event stream:
+//
+//	GET /admin/events?type=&policy=  tail events as Server-Sent Events
+func (h *Handler) HandleEvents(stream *events.Stream) {
+	h.mux.Handle("GET /admin/events", stream)
+}
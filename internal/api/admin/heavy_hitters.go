@@ -0,0 +1,63 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+)
+
+type heavyHitterView struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+	// Error bounds how much Count may overestimate.
+	Error uint64 `json:"error"`
+}
+
+type policyHeavyHittersView struct {
+	Policy  string            `json:"policy"`
+	Active  []heavyHitterView `json:"active"`
+	Limited []heavyHitterView `json:"limited"`
+}
+
+// HandleHeavyHitters registers GET /admin/heavy-hitters?n=20[&policy=name],
+// listing the most active and most limited keys per policy.
+func (h *Handler) HandleHeavyHitters(manager *limiter.Manager) {
+	h.mux.HandleFunc("GET /admin/heavy-hitters", func(w http.ResponseWriter, r *http.Request) {
+		n := 20
+		if raw := r.URL.Query().Get("n"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed <= 0 {
+				writeError(w, http.StatusBadRequest, "n must be a positive integer")
+				return
+			}
+			n = parsed
+		}
+		hitters := manager.HeavyHitters(n)
+		if hitters == nil {
+			writeError(w, http.StatusNotFound, "heavy hitter tracking is not enabled")
+			return
+		}
+		only := r.URL.Query().Get("policy")
+		views := make([]policyHeavyHittersView, 0, len(hitters))
+		for _, p := range hitters {
+			if only != "" && p.Policy != only {
+				continue
+			}
+			views = append(views, policyHeavyHittersView{
+				Policy:  p.Policy,
+				Active:  heavyHitterViews(p.Active),
+				Limited: heavyHitterViews(p.Limited),
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"policies": views})
+	})
+}
+
+func heavyHitterViews(hitters []limiter.HeavyHitter) []heavyHitterView {
+	views := make([]heavyHitterView, len(hitters))
+	for i, h := range hitters {
+		views[i] = heavyHitterView{Key: h.Key, Count: h.Count, Error: h.Error}
+	}
+	return views
+}
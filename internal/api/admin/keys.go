@@ -0,0 +1,166 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+)
+
+type policyView struct {
+	Name        string   `json:"name"`
+	Algorithm   string   `json:"algorithm,omitempty"`
+	Mode        string   `json:"mode,omitempty"`
+	Routes      []string `json:"routes,omitempty"`
+	Methods     []string `json:"methods,omitempty"`
+	FailureMode string   `json:"failure_mode,omitempty"`
+	Inspectable bool     `json:"inspectable"`
+}
+
+type resultView struct {
+	Allowed      bool  `json:"allowed"`
+	Limit        int   `json:"limit"`
+	Remaining    int   `json:"remaining"`
+	RetryAfterMs int64 `json:"retry_after_ms"`
+	ResetAfterMs int64 `json:"reset_after_ms"`
+	WindowMs     int64 `json:"window_ms"`
+}
+
+type keyView struct {
+	Policy   string `json:"policy"`
+	Key      string `json:"key"`
+	StateKey string `json:"state_key"`
+	Exists   bool   `json:"exists"`
+	State    any    `json:"state"`
+	// Remaining is the quota available now; Next is what one more request would get.
+	Remaining int        `json:"remaining"`
+	Next      resultView `json:"next"`
+}
+
+type grantRequest struct {
+	Requests int `json:"requests"`
+}
+
+// HandleKeys registers policy and key endpoints backed by manager:
+//
+//	GET    /admin/policies                           list policies
+//	GET    /admin/policies/{policy}/keys?cursor=n    list keys holding state, one batch at a time
+//	DELETE /admin/policies/{policy}/keys             reset every key of the policy
+//	GET    /admin/policies/{policy}/keys/{key}       inspect a key without consuming quota
+//	DELETE /admin/policies/{policy}/keys/{key}       reset a key to full quota
+//	POST   /admin/policies/{policy}/keys/{key}/grant grant {"requests": n} extra quota
+func (h *Handler) HandleKeys(manager *limiter.Manager) {
+	h.mux.HandleFunc("GET /admin/policies", func(w http.ResponseWriter, r *http.Request) {
+		policies := manager.Policies()
+		views := make([]policyView, 0, len(policies))
+		for _, p := range policies {
+			_, inspectable := p.Limiter.(limiter.Inspector)
+			views = append(views, policyView{
+				Name:        p.Name,
+				Algorithm:   string(p.Algorithm),
+				Mode:        string(p.Mode),
+				Routes:      p.Routes,
+				Methods:     p.Methods,
+				FailureMode: string(p.FailureMode),
+				Inspectable: inspectable,
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"policies": views})
+	})
+
+	h.mux.HandleFunc("GET /admin/policies/{policy}/keys", func(w http.ResponseWriter, r *http.Request) {
+		policy := r.PathValue("policy")
+		var cursor uint64
+		if raw := r.URL.Query().Get("cursor"); raw != "" {
+			var err error
+			if cursor, err = strconv.ParseUint(raw, 10, 64); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid cursor")
+				return
+			}
+		}
+		keys, next, err := manager.ScanKeys(r.Context(), policy, cursor)
+		if err != nil {
+			writeLimiterError(w, err)
+			return
+		}
+		if keys == nil {
+			keys = []string{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"policy": policy, "keys": keys, "cursor": next})
+	})
+
+	h.mux.HandleFunc("DELETE /admin/policies/{policy}/keys", func(w http.ResponseWriter, r *http.Request) {
+		policy := r.PathValue("policy")
+		n, err := manager.ResetPolicy(r.Context(), policy)
+		if err != nil {
+			writeLimiterError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"policy": policy, "reset": n})
+	})
+
+	h.mux.HandleFunc("GET /admin/policies/{policy}/keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		policy, key := r.PathValue("policy"), r.PathValue("key")
+		state, err := manager.InspectKey(r.Context(), policy, key)
+		if err != nil {
+			writeLimiterError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, keyView{
+			Policy:    policy,
+			Key:       key,
+			StateKey:  state.StateKey,
+			Exists:    state.Exists,
+			State:     state.State,
+			Remaining: state.Remaining,
+			Next: resultView{
+				Allowed:      state.Result.Allowed,
+				Limit:        state.Result.Limit,
+				Remaining:    state.Result.Remaining,
+				RetryAfterMs: state.Result.RetryAfter.Milliseconds(),
+				ResetAfterMs: state.Result.ResetAfter.Milliseconds(),
+				WindowMs:     state.Result.Window.Milliseconds(),
+			},
+		})
+	})
+
+	h.mux.HandleFunc("DELETE /admin/policies/{policy}/keys/{key}", func(w http.ResponseWriter, r *http.Request) {
+		policy, key := r.PathValue("policy"), r.PathValue("key")
+		if err := manager.ResetKey(r.Context(), policy, key); err != nil {
+			writeLimiterError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"policy": policy, "key": key, "reset": true})
+	})
+
+	h.mux.HandleFunc("POST /admin/policies/{policy}/keys/{key}/grant", func(w http.ResponseWriter, r *http.Request) {
+		policy, key := r.PathValue("policy"), r.PathValue("key")
+		var req grantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+		if req.Requests <= 0 {
+			writeError(w, http.StatusBadRequest, "requests must be > 0")
+			return
+		}
+		if err := manager.GrantQuota(r.Context(), policy, key, req.Requests); err != nil {
+			writeLimiterError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"policy": policy, "key": key, "granted": req.Requests})
+	})
+}
+
+func writeLimiterError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, limiter.ErrUnknownPolicy), errors.Is(err, limiter.ErrNoPenalty):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, limiter.ErrNotInspectable):
+		writeError(w, http.StatusNotImplemented, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+)
+
+type overrideView struct {
+	Policy    string    `json:"policy"`
+	Key       string    `json:"key"`
+	Scale     float64   `json:"scale,omitempty"`
+	Limit     int       `json:"limit,omitempty"`
+	Window    string    `json:"window,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type overrideRequest struct {
+	Scale  float64 `json:"scale"`
+	Limit  int     `json:"limit"`
+	Window string  `json:"window"`
+	TTL    string  `json:"ttl"`
+	Reason string  `json:"reason"`
+}
+
+func newOverrideView(o limiter.Override) overrideView {
+	view := overrideView{
+		Policy:    o.Policy,
+		Key:       o.Key,
+		Scale:     o.Scale,
+		Limit:     o.Limit,
+		Reason:    o.Reason,
+		ExpiresAt: o.ExpiresAt,
+	}
+	if o.Window > 0 {
+		view.Window = o.Window.String()
+	}
+	return view
+}
+
+// HandleOverrides registers endpoints managing temporary per-key overrides:
+//
+//	GET    /admin/overrides                 list live overrides
+//	PUT    /admin/overrides/{policy}/{key}  create or replace an override
+//	DELETE /admin/overrides/{policy}/{key}  remove an override
+//
+// A PUT body sets either {"scale": 10} or {"limit": 1, "window": "1s"},
+// plus a required "ttl" such as "2h" and an optional "reason".
+func (h *Handler) HandleOverrides(manager *limiter.Manager) {
+	h.mux.HandleFunc("GET /admin/overrides", func(w http.ResponseWriter, r *http.Request) {
+		overrides, err := manager.Overrides(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		views := make([]overrideView, 0, len(overrides))
+		for _, o := range overrides {
+			views = append(views, newOverrideView(o))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"overrides": views})
+	})
+
+	h.mux.HandleFunc("PUT /admin/overrides/{policy}/{key}", func(w http.ResponseWriter, r *http.Request) {
+		var req overrideRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+			return
+		}
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			writeError(w, http.StatusBadRequest, "ttl must be a positive duration such as 2h")
+			return
+		}
+		override := limiter.Override{
+			Policy:    r.PathValue("policy"),
+			Key:       r.PathValue("key"),
+			Scale:     req.Scale,
+			Limit:     req.Limit,
+			Reason:    req.Reason,
+			ExpiresAt: time.Now().Add(ttl).UTC(),
+		}
+		if req.Window != "" {
+			if override.Window, err = time.ParseDuration(req.Window); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid window: "+err.Error())
+				return
+			}
+		}
+		if err := manager.SetOverride(r.Context(), override); err != nil {
+			if errors.Is(err, limiter.ErrInvalidOverride) {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			writeLimiterError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, newOverrideView(override))
+	})
+
+	h.mux.HandleFunc("DELETE /admin/overrides/{policy}/{key}", func(w http.ResponseWriter, r *http.Request) {
+		policy, key := r.PathValue("policy"), r.PathValue("key")
+		if err := manager.DeleteOverride(r.Context(), policy, key); err != nil {
+			writeLimiterError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"policy": policy, "key": key, "deleted": true})
+	})
+}
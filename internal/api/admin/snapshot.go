@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+// HandleSnapshots registers snapshot export/import endpoints for store:
+//
+//	GET  /admin/snapshot       stream a snapshot
+//	POST /admin/snapshot       import a snapshot from the request body
+//	POST /admin/snapshot/save  write a snapshot to path
+//	POST /admin/snapshot/load  import the snapshot at path
+//
+// keep filters imported keys; path may be empty to disable the file endpoints.
+// Uploaded snapshots larger than maxBytes are refused, and a snapshot that
+// fails to decode is rejected as a whole.
+func (h *Handler) HandleSnapshots(store *storage.MemoryStorage, path string, maxBytes int64, keep func(key string) bool) {
+	h.mux.HandleFunc("GET /admin/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="limiter-snapshot.jsonl"`)
+		_, _ = store.Export(w)
+	})
+
+	h.mux.HandleFunc("POST /admin/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		n, err := store.Import(http.MaxBytesReader(w, r.Body, maxBytes), keep)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "snapshot exceeds snapshot_max_bytes; nothing was imported")
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error()+"; nothing was imported")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"imported": n})
+	})
+
+	h.mux.HandleFunc("POST /admin/snapshot/save", func(w http.ResponseWriter, r *http.Request) {
+		if path == "" {
+			writeError(w, http.StatusNotFound, "snapshot_path is not configured")
+			return
+		}
+		n, err := storage.SaveSnapshotFile(store, path)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"exported": n, "path": path})
+	})
+
+	h.mux.HandleFunc("POST /admin/snapshot/load", func(w http.ResponseWriter, r *http.Request) {
+		if path == "" {
+			writeError(w, http.StatusNotFound, "snapshot_path is not configured")
+			return
+		}
+		n, err := storage.LoadSnapshotFile(store, path, keep)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"imported": n, "path": path})
+	})
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequireClientCert rejects requests whose connection presented no verified
+// client certificate. It lets a listener accept certificate-less probes and
+// scrapes while the routes it wraps still demand mTLS.
+func RequireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !verifiedClient(r.TLS) {
+			http.Error(w, "client certificate required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryRequireClientCertInterceptor is RequireClientCert for unary RPCs. The
+// gRPC health service stays reachable without a certificate for probes.
+func UnaryRequireClientCertInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if strings.HasPrefix(info.FullMethod, "/grpc.health.v1.Health/") || verifiedPeer(ctx) {
+			return handler(ctx, req)
+		}
+		return nil, status.Error(codes.Unauthenticated, "client certificate required")
+	}
+}
+
+func verifiedPeer(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	return ok && verifiedClient(&info.State)
+}
+
+func verifiedClient(state *tls.ConnectionState) bool {
+	return state != nil && len(state.VerifiedChains) > 0
+}
@@ -5,37 +5,64 @@ import (
 	"net/http"
 	"net/textproto"
 	"net/url"
+	"strconv"
+	"strings"
 
 	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"go.opentelemetry.io/otel/attribute"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
-	"google.golang.org/grpc/status"
 )
 
 // UnaryRateLimitInterceptor applies rate limiting to unary RPCs.
-func UnaryRateLimitInterceptor(manager *limiter.Manager, recorder MetricsRecorder) grpc.UnaryServerInterceptor {
+func UnaryRateLimitInterceptor(manager *limiter.Manager, recorder MetricsRecorder, opts ...Option) grpc.UnaryServerInterceptor {
+	o := buildOptions(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		if manager == nil {
 			return handler(ctx, req)
 		}
 
+		md, _ := metadata.FromIncomingContext(ctx)
+		ctx, span := startSpan(ctx, metadataCarrier(md), "ratelimit.grpc", attribute.String("rpc.method", info.FullMethod))
+		defer span.End()
+
 		httpReq := grpcRequestToHTTP(ctx, info.FullMethod)
 		result, policy, matched, err := manager.Allow(ctx, httpReq)
+		annotateSpan(span, result, policy, matched, err)
 		if err != nil {
-			return nil, status.Error(codes.Internal, "rate limiter failure")
+			_ = grpc.SetHeader(ctx, metadata.Pairs(strings.ToLower(headerRetryAfter), strconv.FormatInt(ceilSeconds(storageRetryAfter), 10)))
+			return nil, o.storageError.grpcError()
+		}
+		if matched {
+			observe(recorder, policy, result)
+		}
+		if matched && !result.Shadow {
+			_ = grpc.SetHeader(ctx, rateLimitMetadata(o.styleFor(policy), result, policy))
 		}
-		if matched && recorder != nil {
-			recorder.Observe(policy, result.Allowed)
+		if matched && result.Banned {
+			return nil, o.denied.grpcError()
 		}
 		if matched && !result.Allowed {
-			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+			return nil, o.responseFor(policy).grpcError()
 		}
 		return handler(ctx, req)
 	}
 }
 
+// rateLimitMetadata mirrors the HTTP rate limit headers as response metadata.
+func rateLimitMetadata(style HeaderStyle, result limiter.Result, policy string) metadata.MD {
+	md := metadata.MD{}
+	for _, h := range rateLimitHeaders(style, result, policy) {
+		md.Set(strings.ToLower(h.name), h.value)
+	}
+	if !result.Allowed && result.RetryAfter > 0 {
+		md.Set(strings.ToLower(headerRetryAfter), strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
+	}
+	return md
+}
+
 func grpcRequestToHTTP(ctx context.Context, method string) *http.Request {
 	reqURL := &url.URL{Path: method}
 	httpReq := &http.Request{
@@ -54,8 +81,14 @@ func grpcRequestToHTTP(ctx context.Context, method string) *http.Request {
 		}
 	}
 
-	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
-		httpReq.RemoteAddr = p.Addr.String()
+	if p, ok := peer.FromContext(ctx); ok {
+		if p.Addr != nil {
+			httpReq.RemoteAddr = p.Addr.String()
+		}
+		// Expose the peer's verified client certificate to client_cert identities.
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			httpReq.TLS = &info.State
+		}
 	}
 
 	return httpReq
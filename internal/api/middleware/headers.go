@@ -0,0 +1,157 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+)
+
+// HeaderStyle selects which rate limit response headers are emitted.
+type HeaderStyle string
+
+const (
+	// HeaderStyleLegacy emits the X-RateLimit-* headers only.
+	HeaderStyleLegacy HeaderStyle = "legacy"
+	// HeaderStyleIETF emits the IETF draft RateLimit and RateLimit-Policy headers only.
+	HeaderStyleIETF HeaderStyle = "ietf"
+	// HeaderStyleBoth emits both header sets.
+	HeaderStyleBoth HeaderStyle = "both"
+)
+
+// ParseHeaderStyle validates a configured header style. Empty values map to
+// def; "draft" is accepted as an alias of ietf.
+func ParseHeaderStyle(value string, def HeaderStyle) (HeaderStyle, error) {
+	switch HeaderStyle(strings.ToLower(strings.TrimSpace(value))) {
+	case "":
+		return def, nil
+	case HeaderStyleLegacy:
+		return HeaderStyleLegacy, nil
+	case HeaderStyleIETF, "draft":
+		return HeaderStyleIETF, nil
+	case HeaderStyleBoth:
+		return HeaderStyleBoth, nil
+	default:
+		return "", fmt.Errorf("unsupported header style %s", value)
+	}
+}
+
+// HeaderOptions builds middleware options from server.rate_limit_headers and
+// the per-policy headers overrides.
+func HeaderOptions(server string, policies []config.Policy) ([]Option, error) {
+	style, err := ParseHeaderStyle(server, HeaderStyleLegacy)
+	if err != nil {
+		return nil, fmt.Errorf("rate_limit_headers: %w", err)
+	}
+	opts := []Option{WithHeaderStyle(style)}
+	for _, policy := range policies {
+		if policy.Headers == "" {
+			continue
+		}
+		style, err := ParseHeaderStyle(policy.Headers, "")
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", policy.Name, err)
+		}
+		opts = append(opts, WithPolicyHeaderStyle(policy.Name, style))
+	}
+	return opts, nil
+}
+
+const (
+	headerLimit       = "X-RateLimit-Limit"
+	headerRemaining   = "X-RateLimit-Remaining"
+	headerReset       = "X-RateLimit-Reset"
+	headerPolicy      = "X-RateLimit-Policy"
+	headerRateLimit   = "RateLimit"
+	headerRatePolicy  = "RateLimit-Policy"
+	headerRetryAfter  = "Retry-After"
+	defaultPolicyName = "default"
+)
+
+type header struct {
+	name  string
+	value string
+}
+
+// rateLimitHeaders renders the headers for result in the requested style.
+func rateLimitHeaders(style HeaderStyle, result limiter.Result, policy string) []header {
+	var headers []header
+	if style != HeaderStyleIETF {
+		headers = append(headers, legacyHeaders(result, policy)...)
+	}
+	if style == HeaderStyleIETF || style == HeaderStyleBoth {
+		headers = append(headers, ietfHeaders(result, policy)...)
+	}
+	return headers
+}
+
+func legacyHeaders(result limiter.Result, policy string) []header {
+	var headers []header
+	if result.Limit > 0 {
+		headers = append(headers, header{headerLimit, strconv.Itoa(result.Limit)})
+	}
+	headers = append(headers, header{headerRemaining, strconv.Itoa(result.Remaining)})
+	if result.ResetAfter > 0 {
+		headers = append(headers, header{headerReset, strconv.FormatInt(int64(result.ResetAfter.Seconds()), 10)})
+	}
+	if policy != "" {
+		headers = append(headers, header{headerPolicy, policy})
+	}
+	return headers
+}
+
+// ietfHeaders follows draft-ietf-httpapi-ratelimit-headers, e.g.
+//
+//	RateLimit-Policy: "burst";q=100;w=60;qu="requests"
+//	RateLimit: "burst";r=42;t=12
+func ietfHeaders(result limiter.Result, policy string) []header {
+	if policy == "" {
+		policy = defaultPolicyName
+	}
+	name := sfString(policy)
+
+	var headers []header
+	if result.Limit > 0 {
+		value := name + ";q=" + strconv.Itoa(result.Limit)
+		if w := ceilSeconds(result.Window); w > 0 {
+			value += ";w=" + strconv.FormatInt(w, 10)
+		}
+		value += `;qu="requests"`
+		headers = append(headers, header{headerRatePolicy, value})
+	}
+
+	value := name + ";r=" + strconv.Itoa(result.Remaining)
+	if t := ceilSeconds(result.ResetAfter); t > 0 {
+		value += ";t=" + strconv.FormatInt(t, 10)
+	}
+	headers = append(headers, header{headerRateLimit, value})
+	return headers
+}
+
+// sfString encodes value as a structured field string (RFC 8941).
+func sfString(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range value {
+		if r < 0x20 || r > 0x7e {
+			continue
+		}
+		if r == '"' || r == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -5,15 +5,19 @@ import (
 	"strconv"
 
 	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 // MetricsRecorder exposes the minimal metric hooks used by the middleware.
 type MetricsRecorder interface {
 	Observe(policy string, allowed bool)
+	// ObserveShadowDenied counts a request a shadow mode policy would have rejected.
+	ObserveShadowDenied(policy string)
 }
 
 // RateLimiter applies limiter.Manager checks to HTTP traffic.
-func RateLimiter(manager *limiter.Manager, recorder MetricsRecorder) func(http.Handler) http.Handler {
+func RateLimiter(manager *limiter.Manager, recorder MetricsRecorder, opts ...Option) func(http.Handler) http.Handler {
+	o := buildOptions(opts)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			if manager == nil {
@@ -21,9 +25,15 @@ func RateLimiter(manager *limiter.Manager, recorder MetricsRecorder) func(http.H
 				return
 			}
 
-			result, policyName, matched, err := manager.Allow(r.Context(), r)
+			ctx, span := startSpan(r.Context(), propagation.HeaderCarrier(r.Header), "ratelimit.http", httpSpanAttributes(r)...)
+			defer span.End()
+			r = r.WithContext(ctx)
+
+			result, policyName, matched, err := manager.Allow(ctx, r)
+			annotateSpan(span, result, policyName, matched, err)
 			if err != nil {
-				http.Error(w, "rate limiter error", http.StatusInternalServerError)
+				w.Header().Set(headerRetryAfter, strconv.FormatInt(ceilSeconds(storageRetryAfter), 10))
+				o.storageError.write(w, r, storageErrorData())
 				return
 			}
 
@@ -32,16 +42,23 @@ func RateLimiter(manager *limiter.Manager, recorder MetricsRecorder) func(http.H
 				return
 			}
 
-			decorateHeaders(w, result, policyName)
-			if recorder != nil {
-				recorder.Observe(policyName, result.Allowed)
+			observe(recorder, policyName, result)
+			if result.Shadow {
+				next.ServeHTTP(w, r)
+				return
 			}
 
+			decorateHeaders(w, o.styleFor(policyName), result, policyName)
+
 			if !result.Allowed {
 				if result.RetryAfter > 0 {
-					w.Header().Set("Retry-After", strconv.FormatInt(int64(result.RetryAfter.Seconds()), 10))
+					w.Header().Set(headerRetryAfter, strconv.FormatInt(ceilSeconds(result.RetryAfter), 10))
 				}
-				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				if result.Banned {
+					o.denied.write(w, r, bannedData(result, policyName))
+					return
+				}
+				o.responseFor(policyName).write(w, r, rateLimitedData(result, policyName))
 				return
 			}
 
@@ -50,15 +67,19 @@ func RateLimiter(manager *limiter.Manager, recorder MetricsRecorder) func(http.H
 	}
 }
 
-func decorateHeaders(w http.ResponseWriter, result limiter.Result, policy string) {
-	if result.Limit > 0 {
-		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+func observe(recorder MetricsRecorder, policy string, result limiter.Result) {
+	if recorder == nil {
+		return
 	}
-	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
-	if result.ResetAfter > 0 {
-		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(int64(result.ResetAfter.Seconds()), 10))
+	if result.ShadowDenied {
+		recorder.ObserveShadowDenied(policy)
+		return
 	}
-	if policy != "" {
-		w.Header().Set("X-RateLimit-Policy", policy)
+	recorder.Observe(policy, result.Allowed)
+}
+
+func decorateHeaders(w http.ResponseWriter, style HeaderStyle, result limiter.Result, policy string) {
+	for _, h := range rateLimitHeaders(style, result, policy) {
+		w.Header().Set(h.name, h.value)
 	}
 }
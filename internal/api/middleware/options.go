@@ -0,0 +1,93 @@
+package middleware
+
+// Option customizes the HTTP middleware and gRPC interceptor.
+type Option func(*options)
+
+type options struct {
+	headerStyle     HeaderStyle
+	policyStyles    map[string]HeaderStyle
+	rateLimited     Response
+	denied          Response
+	storageError    Response
+	policyResponses map[string]Response
+}
+
+func buildOptions(opts []Option) options {
+	o := options{
+		headerStyle:     HeaderStyleLegacy,
+		policyStyles:    map[string]HeaderStyle{},
+		rateLimited:     DefaultRateLimitedResponse,
+		denied:          DefaultDeniedResponse,
+		storageError:    DefaultStorageErrorResponse,
+		policyResponses: map[string]Response{},
+	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// WithHeaderStyle sets the server-wide header style. Policies may override it.
+func WithHeaderStyle(style HeaderStyle) Option {
+	return func(o *options) {
+		if style != "" {
+			o.headerStyle = style
+		}
+	}
+}
+
+// WithPolicyHeaderStyle overrides the header style for a single policy.
+func WithPolicyHeaderStyle(policy string, style HeaderStyle) Option {
+	return func(o *options) {
+		if style != "" {
+			o.policyStyles[policy] = style
+		}
+	}
+}
+
+// WithRateLimitedResponse sets the default response for rate limited requests.
+func WithRateLimitedResponse(resp Response) Option {
+	return func(o *options) {
+		o.rateLimited = resp
+	}
+}
+
+// WithPolicyResponse overrides the rate limited response for a single policy.
+func WithPolicyResponse(policy string, resp Response) Option {
+	return func(o *options) {
+		o.policyResponses[policy] = resp
+	}
+}
+
+// WithDeniedResponse sets the response for requests of banned keys. Bans
+// are temporary, so the default is 429 with Retry-After until the ban ends.
+func WithDeniedResponse(resp Response) Option {
+	return func(o *options) {
+		o.denied = resp
+	}
+}
+
+// WithStorageErrorResponse sets the response used when the limiter itself fails.
+func WithStorageErrorResponse(resp Response) Option {
+	return func(o *options) {
+		o.storageError = resp
+	}
+}
+
+// responseFor resolves the rate limited response for the named policy.
+func (o options) responseFor(policyName string) Response {
+	if resp, ok := o.policyResponses[policyName]; ok {
+		return resp
+	}
+	return o.rateLimited
+}
+
+// styleFor resolves the header style for the named policy.
+func (o options) styleFor(policyName string) HeaderStyle {
+	if style, ok := o.policyStyles[policyName]; ok {
+		return style
+	}
+	return o.headerStyle
+}
@@ -0,0 +1,211 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	textContentType    = "text/plain; charset=utf-8"
+)
+
+// problemTemplate renders an RFC 9457 problem details document.
+const problemTemplate = `{"type":"about:blank","title":{{json .Title}},"status":{{.Status}},"detail":{{json .Detail}},"instance":{{json .Instance}}` +
+	`{{if .Policy}},"policy":{{json .Policy}}{{end}}{{if .Limit}},"limit":{{.Limit}}{{end}}{{if .RetryAfter}},"retry_after":{{.RetryAfter}}{{end}}}`
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Response is a compiled rejection response. Code and Message are used for
+// gRPC calls.
+type Response struct {
+	Status      int
+	ContentType string
+	Code        codes.Code
+	Message     string
+	body        *template.Template
+}
+
+// ResponseData is the data available to response body templates.
+type ResponseData struct {
+	Status     int
+	Title      string
+	Detail     string
+	Instance   string
+	Method     string
+	Policy     string
+	Limit      int
+	Remaining  int
+	RetryAfter int64
+}
+
+// Default responses used when nothing is configured.
+var (
+	DefaultRateLimitedResponse  = mustResponse(http.StatusTooManyRequests, codes.ResourceExhausted, "rate limit exceeded")
+	DefaultDeniedResponse       = mustResponse(http.StatusTooManyRequests, codes.ResourceExhausted, "temporarily banned after repeated violations")
+	DefaultStorageErrorResponse = mustResponse(http.StatusServiceUnavailable, codes.Unavailable, "rate limiter unavailable")
+)
+
+// storageRetryAfter is advertised when the limiter fails closed; storage
+// outages are expected to be short or to trip a fallback quickly.
+const storageRetryAfter = time.Second
+
+// NewResponse compiles cfg, filling unset fields from def.
+func NewResponse(cfg config.ResponseConfig, def Response) (Response, error) {
+	resp := def
+	if cfg.Status != 0 {
+		if cfg.Status < 400 || cfg.Status > 599 {
+			return Response{}, fmt.Errorf("response status %d must be a 4xx or 5xx code", cfg.Status)
+		}
+		resp.Status = cfg.Status
+	}
+	if cfg.Body != "" {
+		tmpl, err := template.New("response").Funcs(templateFuncs).Parse(cfg.Body)
+		if err != nil {
+			return Response{}, fmt.Errorf("response body: %w", err)
+		}
+		resp.body = tmpl
+		resp.ContentType = textContentType
+	}
+	if cfg.ContentType != "" {
+		resp.ContentType = cfg.ContentType
+	}
+	if cfg.GRPCCode != "" {
+		code, err := parseCode(cfg.GRPCCode)
+		if err != nil {
+			return Response{}, err
+		}
+		resp.Code = code
+	}
+	if cfg.GRPCMessage != "" {
+		resp.Message = cfg.GRPCMessage
+	}
+	return resp, nil
+}
+
+// parseCode reads a gRPC status code name such as RESOURCE_EXHAUSTED.
+func parseCode(name string) (codes.Code, error) {
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(name)))); err != nil {
+		return 0, fmt.Errorf("response grpc_code %q is not a gRPC status code", name)
+	}
+	if code == codes.OK {
+		return 0, fmt.Errorf("response grpc_code must not be OK")
+	}
+	return code, nil
+}
+
+func mustResponse(status int, code codes.Code, message string) Response {
+	return Response{
+		Status:      status,
+		ContentType: problemContentType,
+		Code:        code,
+		Message:     message,
+		body:        template.Must(template.New("response").Funcs(templateFuncs).Parse(problemTemplate)),
+	}
+}
+
+// grpcError returns the response as a gRPC status error.
+func (resp Response) grpcError() error {
+	return status.Error(resp.Code, resp.Message)
+}
+
+// write renders the response. Template failures fall back to a plain status line.
+func (resp Response) write(w http.ResponseWriter, r *http.Request, data ResponseData) {
+	data.Status = resp.Status
+	data.Title = http.StatusText(resp.Status)
+	data.Instance = r.URL.Path
+	data.Method = r.Method
+
+	var buf bytes.Buffer
+	if resp.body != nil {
+		if err := resp.body.Execute(&buf, data); err != nil {
+			http.Error(w, data.Title, resp.Status)
+			return
+		}
+	}
+	if resp.ContentType != "" {
+		w.Header().Set("Content-Type", resp.ContentType)
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(buf.Bytes())
+}
+
+func rateLimitedData(result limiter.Result, policy string) ResponseData {
+	detail := "rate limit exceeded"
+	if policy != "" {
+		detail = fmt.Sprintf("rate limit exceeded for policy %s", policy)
+	}
+	return ResponseData{
+		Detail:     detail,
+		Policy:     policy,
+		Limit:      result.Limit,
+		Remaining:  result.Remaining,
+		RetryAfter: ceilSeconds(result.RetryAfter),
+	}
+}
+
+func storageErrorData() ResponseData {
+	return ResponseData{
+		Detail:     "rate limiter unavailable",
+		RetryAfter: ceilSeconds(storageRetryAfter),
+	}
+}
+
+func bannedData(result limiter.Result, policy string) ResponseData {
+	return ResponseData{
+		Detail:     fmt.Sprintf("temporarily banned by policy %s after repeated violations", policy),
+		Policy:     policy,
+		RetryAfter: ceilSeconds(result.RetryAfter),
+	}
+}
+
+// ResponseOptions builds middleware options from the server and policy configuration.
+func ResponseOptions(server config.ResponsesConfig, policies []config.Policy) ([]Option, error) {
+	limited, err := NewResponse(server.RateLimited, DefaultRateLimitedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("responses.rate_limited: %w", err)
+	}
+	denied, err := NewResponse(server.Denied, DefaultDeniedResponse)
+	if err != nil {
+		return nil, fmt.Errorf("responses.denied: %w", err)
+	}
+	storageErr, err := NewResponse(server.StorageError, DefaultStorageErrorResponse)
+	if err != nil {
+		return nil, fmt.Errorf("responses.storage_error: %w", err)
+	}
+
+	opts := []Option{
+		WithRateLimitedResponse(limited),
+		WithDeniedResponse(denied),
+		WithStorageErrorResponse(storageErr),
+	}
+	for _, policy := range policies {
+		if policy.Response.IsZero() {
+			continue
+		}
+		resp, err := NewResponse(policy.Response, limited)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", policy.Name, err)
+		}
+		opts = append(opts, WithPolicyResponse(policy.Name, resp))
+	}
+	return opts, nil
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"net/http"
 	"time"
@@ -18,6 +19,8 @@ type HTTPConfig struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	// TLS serves HTTPS when set.
+	TLS *tls.Config
 }
 
 // NewHTTPServer builds an HTTP server with the provided handler.
@@ -28,6 +31,7 @@ func NewHTTPServer(cfg HTTPConfig, handler http.Handler) *HTTPServer {
 		ReadTimeout:  cfg.ReadTimeout,
 		WriteTimeout: cfg.WriteTimeout,
 		IdleTimeout:  cfg.IdleTimeout,
+		TLSConfig:    cfg.TLS,
 	}
 	return &HTTPServer{server: srv}
 }
@@ -37,6 +41,9 @@ func (s *HTTPServer) Start() error {
 	if s.server == nil {
 		return fmt.Errorf("http server not configured")
 	}
+	if s.server.TLSConfig != nil {
+		return s.server.ListenAndServeTLS("", "")
+	}
 	return s.server.ListenAndServe()
 }
 
@@ -2,15 +2,32 @@ package server
 
 import (
 	"net/http"
+	"runtime"
+	"runtime/debug"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/events"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
 )
 
 // Metrics exposes Prometheus counters for limiter behavior.
 type Metrics struct {
-	registry *prometheus.Registry
-	requests *prometheus.CounterVec
+	registry      *prometheus.Registry
+	requests      *prometheus.CounterVec
+	storageErrors *prometheus.CounterVec
+	stateResets   *prometheus.CounterVec
+	circuitState  prometheus.Gauge
+	circuitTrips  *prometheus.CounterVec
+	activeKeys    *prometheus.GaugeVec
+	decisions     *prometheus.HistogramVec
+	storageOps    *prometheus.HistogramVec
+	storageOpErrs *prometheus.CounterVec
+	watched       *prometheus.GaugeVec
+	configInfo    *prometheus.GaugeVec
+	policyInfo    *prometheus.GaugeVec
 }
 
 // NewMetrics registers metrics with a fresh registry.
@@ -21,11 +38,88 @@ func NewMetrics() *Metrics {
 		Name:      "requests_total",
 		Help:      "Total requests processed by the rate limiter",
 	}, []string{"policy", "result"})
-	reg.MustRegister(requests)
+	storageErrors := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rate_limiter",
+		Name:      "storage_errors_total",
+		Help:      "Limiter failures by policy and the failure mode applied",
+	}, []string{"policy", "failure_mode"})
+	stateResets := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rate_limiter",
+		Name:      "state_resets_total",
+		Help:      "Stored limiter state discarded because this build cannot decode it",
+	}, []string{"policy"})
+	circuitState := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "rate_limiter",
+		Name:      "storage_circuit_state",
+		Help:      "Storage circuit breaker state (0=closed, 1=half_open, 2=open)",
+	})
+	circuitTrips := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rate_limiter",
+		Name:      "storage_circuit_transitions_total",
+		Help:      "Storage circuit breaker transitions by target state",
+	}, []string{"state"})
+	activeKeys := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "rate_limiter",
+		Name:      "active_keys",
+		Help:      "Distinct keys with stored limiter state by policy",
+	}, []string{"policy"})
+	decisions := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "rate_limiter",
+		Name:      "decision_duration_seconds",
+		Help:      "Time taken to reach a limiter decision by policy and algorithm",
+		Buckets:   prometheus.ExponentialBuckets(0.00005, 2, 14),
+	}, []string{"policy", "algorithm"})
+	storageOps := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "rate_limiter",
+		Name:      "storage_operation_duration_seconds",
+		Help:      "Storage operation latency by driver and operation",
+		Buckets:   prometheus.ExponentialBuckets(0.00005, 2, 14),
+	}, []string{"driver", "operation"})
+	storageOpErrs := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "rate_limiter",
+		Name:      "storage_operation_errors_total",
+		Help:      "Failed storage operations by driver and operation",
+	}, []string{"driver", "operation"})
+	watched := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "rate_limiter",
+		Name:      "watched_key_remaining",
+		Help:      "Remaining quota of keys listed in metrics.watched_keys",
+	}, []string{"policy", "key"})
+	buildInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "rate_limiter",
+		Name:      "build_info",
+		Help:      "Build information of the running binary; always 1",
+	}, []string{"version", "revision", "go_version"})
+	configInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "rate_limiter",
+		Name:      "config_info",
+		Help:      "Hash of the loaded configuration and the storage driver; always 1",
+	}, []string{"config_hash", "storage_driver"})
+	policyInfo := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "rate_limiter",
+		Name:      "policy_info",
+		Help:      "Configured policies with their algorithm and mode; always 1",
+	}, []string{"policy", "algorithm", "mode"})
+	reg.MustRegister(requests, storageErrors, stateResets, circuitState, circuitTrips, activeKeys,
+		decisions, storageOps, storageOpErrs, watched, buildInfo, configInfo, policyInfo)
+
+	version, revision := buildVersion()
+	buildInfo.WithLabelValues(version, revision, runtime.Version()).Set(1)
 
 	return &Metrics{
-		registry: reg,
-		requests: requests,
+		registry:      reg,
+		requests:      requests,
+		storageErrors: storageErrors,
+		stateResets:   stateResets,
+		circuitState:  circuitState,
+		circuitTrips:  circuitTrips,
+		activeKeys:    activeKeys,
+		decisions:     decisions,
+		storageOps:    storageOps,
+		storageOpErrs: storageOpErrs,
+		watched:       watched,
+		configInfo:    configInfo,
+		policyInfo:    policyInfo,
 	}
 }
 
@@ -44,6 +138,162 @@ func (m *Metrics) Observe(policy string, allowed bool) {
 	m.requests.WithLabelValues(policy, status).Inc()
 }
 
+// ObserveShadowDenied counts a request a shadow mode policy would have
+// rejected. It is recorded as result="shadow_limited" so it never mixes with
+// enforced rejections.
+func (m *Metrics) ObserveShadowDenied(policy string) {
+	if m == nil {
+		return
+	}
+	m.requests.WithLabelValues(policy, "shadow_limited").Inc()
+}
+
+// ObserveStorageError counts a limiter failure.
+func (m *Metrics) ObserveStorageError(policy string, mode string) {
+	if m == nil {
+		return
+	}
+	m.storageErrors.WithLabelValues(policy, mode).Inc()
+}
+
+// ObserveStateReset counts state discarded as unreadable.
+func (m *Metrics) ObserveStateReset(policy string) {
+	if m == nil {
+		return
+	}
+	m.stateResets.WithLabelValues(policy).Inc()
+}
+
+// ObserveCircuitState records a storage circuit breaker transition.
+func (m *Metrics) ObserveCircuitState(_, to storage.CircuitState) {
+	if m == nil {
+		return
+	}
+	m.circuitState.Set(float64(to))
+	m.circuitTrips.WithLabelValues(to.String()).Inc()
+}
+
+// ObserveActiveKeys records the number of keys holding state for policy.
+func (m *Metrics) ObserveActiveKeys(policy string, n int) {
+	if m == nil {
+		return
+	}
+	m.activeKeys.WithLabelValues(policy).Set(float64(n))
+}
+
+// ObserveDecision records how long a limiter decision took.
+func (m *Metrics) ObserveDecision(policy, algorithm string, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	m.decisions.WithLabelValues(policy, algorithm).Observe(duration.Seconds())
+}
+
+// ObserveStorageOp records the latency and outcome of a storage operation.
+func (m *Metrics) ObserveStorageOp(driver, op string, duration time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.storageOps.WithLabelValues(driver, op).Observe(duration.Seconds())
+	if err != nil {
+		m.storageOpErrs.WithLabelValues(driver, op).Inc()
+	}
+}
+
+// ObserveWatchedKey records the remaining quota of a watched key.
+func (m *Metrics) ObserveWatchedKey(policy, key string, remaining int) {
+	if m == nil {
+		return
+	}
+	m.watched.WithLabelValues(policy, key).Set(float64(remaining))
+}
+
+// SetConfigInfo publishes the configuration hash and storage driver.
+func (m *Metrics) SetConfigInfo(hash, driver string) {
+	if m == nil {
+		return
+	}
+	m.configInfo.Reset()
+	m.configInfo.WithLabelValues(hash, driver).Set(1)
+}
+
+// SetPolicyInfo publishes one configured policy.
+func (m *Metrics) SetPolicyInfo(policy, algorithm, mode string) {
+	if m == nil {
+		return
+	}
+	m.policyInfo.WithLabelValues(policy, algorithm, mode).Set(1)
+}
+
+// RegisterHeavyHitters exports the top n active and limited keys per policy
+// as rate_limiter_heavy_hitter_requests, computed at scrape time so the label
+// set never exceeds policies x 2 x n. Keys are exported hashed with the
+// manager's KeyHasher, as in logs, because /metrics is unauthenticated.
+func (m *Metrics) RegisterHeavyHitters(manager *limiter.Manager, n int) {
+	if m == nil || manager == nil || n <= 0 {
+		return
+	}
+	m.registry.MustRegister(&heavyHitterCollector{manager: manager, n: n})
+}
+
+var heavyHitterDesc = prometheus.NewDesc(
+	"rate_limiter_heavy_hitter_requests",
+	"Approximate requests of the most active and most limited keys per policy",
+	[]string{"policy", "kind", "key_hash"}, nil,
+)
+
+type heavyHitterCollector struct {
+	manager *limiter.Manager
+	n       int
+}
+
+func (c *heavyHitterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- heavyHitterDesc
+}
+
+func (c *heavyHitterCollector) Collect(ch chan<- prometheus.Metric) {
+	hasher := c.manager.KeyHasher()
+	for _, policy := range c.manager.HeavyHitters(c.n) {
+		for _, h := range policy.Active {
+			ch <- prometheus.MustNewConstMetric(heavyHitterDesc, prometheus.GaugeValue, float64(h.Count), policy.Policy, "active", hasher.Hash(h.Key))
+		}
+		for _, h := range policy.Limited {
+			ch <- prometheus.MustNewConstMetric(heavyHitterDesc, prometheus.GaugeValue, float64(h.Count), policy.Policy, "limited", hasher.Hash(h.Key))
+		}
+	}
+}
+
+// RegisterEventBus exports how many events bus dropped because a sink was behind.
+func (m *Metrics) RegisterEventBus(bus *events.Bus) {
+	if m == nil || bus == nil {
+		return
+	}
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: "rate_limiter",
+		Name:      "events_dropped_total",
+		Help:      "Events discarded because a sink queue was full",
+	}, func() float64 { return float64(bus.Dropped()) }))
+}
+
+// RegisterMemoryStorage exports entry count and eviction gauges for store.
+func (m *Metrics) RegisterMemoryStorage(store *storage.MemoryStorage) {
+	if m == nil || store == nil {
+		return
+	}
+	m.registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "rate_limiter",
+			Name:      "memory_storage_entries",
+			Help:      "Entries currently held by the in-memory storage",
+		}, func() float64 { return float64(store.Len()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: "rate_limiter",
+			Name:      "memory_storage_evictions_total",
+			Help:      "Entries evicted from the in-memory storage to respect max_entries",
+		}, func() float64 { return float64(store.Evictions()) }),
+	)
+}
+
 // Handler returns an HTTP handler serving the registry.
 func (m *Metrics) Handler() http.Handler {
 	if m == nil {
@@ -51,3 +301,21 @@ func (m *Metrics) Handler() http.Handler {
 	}
 	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
 }
+
+// buildVersion reads the module version and VCS revision embedded by the Go toolchain.
+func buildVersion() (string, string) {
+	version, revision := "unknown", "unknown"
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return version, revision
+	}
+	if info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			revision = setting.Value
+		}
+	}
+	return version, revision
+}
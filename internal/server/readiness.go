@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// Readiness decides whether the server should receive traffic: the
+// configuration must be loaded, storage must answer a ping within the
+// timeout and the server must not be draining. A ResilientStorage keeps
+// serving from its local fallback while the primary is down, so its failed
+// ping only reports storage as degraded; otherwise one storage outage would
+// take every replica out of rotation at once.
+type Readiness struct {
+	store   storage.Storage
+	timeout time.Duration
+
+	configLoaded atomic.Bool
+	draining     atomic.Bool
+}
+
+// ReadinessReport is the outcome of one readiness check. Checks maps each
+// check to "ok" or the reason it failed.
+type ReadinessReport struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+// NewReadiness checks store with a ping bounded by timeout.
+func NewReadiness(store storage.Storage, timeout time.Duration) *Readiness {
+	return &Readiness{store: store, timeout: timeout}
+}
+
+// MarkConfigLoaded records that the configuration has been loaded and every
+// component built from it.
+func (r *Readiness) MarkConfigLoaded() {
+	r.configLoaded.Store(true)
+}
+
+// Drain reports not ready from now on, so load balancers stop routing new
+// traffic while in-flight requests complete.
+func (r *Readiness) Drain() {
+	r.draining.Store(true)
+}
+
+// Check runs every readiness check.
+func (r *Readiness) Check(ctx context.Context) ReadinessReport {
+	report := ReadinessReport{Ready: true, Checks: map[string]string{}}
+	fail := func(name, reason string) {
+		report.Ready = false
+		report.Checks[name] = reason
+	}
+
+	if r.configLoaded.Load() {
+		report.Checks["config"] = "ok"
+	} else {
+		fail("config", "not loaded")
+	}
+
+	if r.draining.Load() {
+		fail("draining", "shutting down")
+	} else {
+		report.Checks["draining"] = "ok"
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	resilient, hasFallback := r.store.(*storage.ResilientStorage)
+	if err := storage.Ping(pingCtx, r.store); err == nil {
+		report.Checks["storage"] = "ok"
+	} else if hasFallback {
+		report.Checks["storage"] = "degraded: " + err.Error()
+	} else {
+		fail("storage", err.Error())
+	}
+	if hasFallback {
+		report.Checks["storage_circuit"] = resilient.State().String()
+	}
+	return report
+}
+
+// Handler serves the readiness report, with 503 when not ready.
+func (r *Readiness) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := r.Check(req.Context())
+		status := http.StatusOK
+		if !report.Ready {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
+
+// Watch mirrors readiness into the overall status of the gRPC health server
+// every interval until ctx is done.
+func (r *Readiness) Watch(ctx context.Context, healthServer *health.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		r.UpdateHealth(ctx, healthServer)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// UpdateHealth sets the gRPC health status from a fresh readiness check.
+func (r *Readiness) UpdateHealth(ctx context.Context, healthServer *health.Server) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if !r.Check(ctx).Ready {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	healthServer.SetServingStatus("", status)
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// TLSConfig names the files a listener's certificate is loaded from.
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile enables mTLS: client certificates are verified against it.
+	ClientCAFile string
+	ClientAuth   tls.ClientAuthType
+	MinVersion   uint16
+	// PeerCAFile verifies cluster peers. It is kept apart from ClientCAFile
+	// so API client certificates cannot authenticate as a node.
+	PeerCAFile string
+}
+
+// ParseTLSVersion maps "1.2" and "1.3" to their tls constants; empty means 1.2.
+func ParseTLSVersion(value string) (uint16, error) {
+	switch value {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unsupported tls version %q (want 1.2 or 1.3)", value)
+	}
+}
+
+// ParseClientAuth maps none, request, verify_if_given and require to a
+// tls.ClientAuthType. Empty means verify_if_given when hasCA is set, so probes
+// and scrapes without a certificate can still connect, and none otherwise.
+func ParseClientAuth(value string, hasCA bool) (tls.ClientAuthType, error) {
+	switch value {
+	case "":
+		if hasCA {
+			return tls.VerifyClientCertIfGiven, nil
+		}
+		return tls.NoClientCert, nil
+	case "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("unknown client_auth %q", value)
+	}
+}
+
+// CertReloader serves a certificate and client CA pool that are reloaded when
+// their files change, so certificates can be rotated without a restart.
+// Handshakes always use the last configuration that loaded successfully.
+type CertReloader struct {
+	cfg     TLSConfig
+	current atomic.Pointer[tls.Config]
+	peerCAs atomic.Pointer[x509.CertPool]
+	stamps  map[string]fileStamp
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewCertReloader loads cfg's files, failing if they are unusable.
+func NewCertReloader(cfg TLSConfig) (*CertReloader, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("tls cert_file and key_file are required")
+	}
+	if cfg.ClientAuth >= tls.VerifyClientCertIfGiven && cfg.ClientCAFile == "" {
+		return nil, errors.New("tls client_ca_file is required to verify client certificates")
+	}
+	r := &CertReloader{cfg: cfg}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the certificate, key and CA files again.
+func (r *CertReloader) Reload() error {
+	stamps := r.stat()
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return fmt.Errorf("tls certificate: %w", err)
+	}
+	next := &tls.Config{
+		MinVersion:   r.cfg.MinVersion,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   r.cfg.ClientAuth,
+	}
+	if r.cfg.ClientCAFile != "" {
+		if next.ClientCAs, err = loadCertPool(r.cfg.ClientCAFile, "tls client_ca_file"); err != nil {
+			return err
+		}
+	}
+	var peerCAs *x509.CertPool
+	if r.cfg.PeerCAFile != "" {
+		if peerCAs, err = loadCertPool(r.cfg.PeerCAFile, "cluster peer_ca_file"); err != nil {
+			return err
+		}
+	}
+	r.peerCAs.Store(peerCAs)
+	r.current.Store(next)
+	r.stamps = stamps
+	return nil
+}
+
+func loadCertPool(file, setting string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", setting, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", setting)
+	}
+	return pool, nil
+}
+
+// ServerConfig returns a tls.Config resolving to the current certificate on
+// every handshake, advertising nextProtos via ALPN.
+func (r *CertReloader) ServerConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: r.cfg.MinVersion,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cfg := r.current.Load().Clone()
+			cfg.NextProtos = nextProtos
+			return cfg, nil
+		},
+	}
+}
+
+// PeerConfigs returns both sides of mutual TLS between replicas. Each side
+// presents the listener certificate and verifies the other against the peer
+// CA, whatever ClientAuth is set to. Incoming peers must also hold a
+// certificate valid for one of hosts, the configured members, so a
+// certificate from the peer CA issued for anything else is refused too. It
+// fails without a peer CA.
+func (r *CertReloader) PeerConfigs(hosts []string) (serverCfg, clientCfg *tls.Config, err error) {
+	if r.cfg.PeerCAFile == "" {
+		return nil, nil, errors.New("cluster peer_ca_file is required to authenticate peers")
+	}
+	if len(hosts) == 0 {
+		return nil, nil, errors.New("cluster peers are required to authenticate peers")
+	}
+	serverCfg = &tls.Config{
+		MinVersion: r.cfg.MinVersion,
+		NextProtos: []string{"h2"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cfg := r.current.Load().Clone()
+			cfg.NextProtos = []string{"h2"}
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			cfg.ClientCAs = r.peerCAs.Load()
+			cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+				return verifyPeerHost(cs, hosts)
+			}
+			return cfg, nil
+		},
+	}
+	clientCfg = &tls.Config{
+		MinVersion: r.cfg.MinVersion,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &r.current.Load().Certificates[0], nil
+		},
+		// The server certificate is verified by verifyPeer against the
+		// current CA pool, so a rotated CA applies to new connections.
+		InsecureSkipVerify: true,
+		VerifyConnection:   r.verifyPeer,
+	}
+	return serverCfg, clientCfg, nil
+}
+
+func (r *CertReloader) verifyPeer(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: peer presented no certificate")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         r.peerCAs.Load(),
+		Intermediates: intermediates,
+		DNSName:       cs.ServerName,
+	})
+	return err
+}
+
+// verifyPeerHost accepts a client certificate valid for one of hosts.
+func verifyPeerHost(cs tls.ConnectionState, hosts []string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: peer presented no certificate")
+	}
+	for _, host := range hosts {
+		if cs.PeerCertificates[0].VerifyHostname(host) == nil {
+			return nil
+		}
+	}
+	return errors.New("tls: peer certificate is not valid for any cluster member")
+}
+
+// Watch reloads the files whenever their size or modification time changes,
+// checking every interval until ctx is done. Failed reloads are logged and
+// the previous certificate stays in use.
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !r.changed() {
+			continue
+		}
+		if err := r.Reload(); err != nil {
+			slog.Error("failed to reload tls certificate", "cert_file", r.cfg.CertFile, "error", err)
+			continue
+		}
+		slog.Info("reloaded tls certificate", "cert_file", r.cfg.CertFile)
+	}
+}
+
+func (r *CertReloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	for _, file := range []string{r.cfg.ClientCAFile, r.cfg.PeerCAFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (r *CertReloader) stat() map[string]fileStamp {
+	stamps := make(map[string]fileStamp, 3)
+	for _, file := range r.files() {
+		if info, err := os.Stat(file); err == nil {
+			stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return stamps
+}
+
+func (r *CertReloader) changed() bool {
+	stamps := r.stat()
+	for _, file := range r.files() {
+		if stamps[file] != r.stamps[file] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,384 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Config describes this node's view of the cluster.
+type Config struct {
+	// Self is this node's address as it appears in Peers.
+	Self string
+	// Peers is the static member list, including Self.
+	Peers []string
+	// BatchWindow is how long forwarded calls wait to share one RPC.
+	BatchWindow time.Duration
+	// MaxBatch sends a batch immediately once it holds this many calls.
+	MaxBatch int
+	// RequestTimeout bounds each forwarded RPC.
+	RequestTimeout time.Duration
+	// DownBackoff is how long a peer that failed is skipped in favour of the local fallback.
+	DownBackoff time.Duration
+	// VirtualNodes is the number of ring positions per member.
+	VirtualNodes int
+	// TLS is the client side of mutual TLS between peers and is required
+	// with more than one member. The peer listener must require client
+	// certificates from the same peer CA.
+	TLS *tls.Config
+	// Insecure allows plaintext, unauthenticated peers. Anyone who can
+	// reach the peer port can then consume or reset any key's quota.
+	Insecure bool
+	// DialOptions are appended to the defaults (transport and codec).
+	DialOptions []grpc.DialOption
+}
+
+// Node routes every rate limit key to its owning peer. Keys owned by this
+// node are evaluated locally; the rest are forwarded in batches, falling back
+// to local evaluation while the owner is unreachable.
+type Node struct {
+	cfg   Config
+	ring  *Ring
+	peers map[string]*peer
+
+	mu       sync.RWMutex
+	policies map[string]limiter.Limiter
+}
+
+// NewNode validates cfg and prepares (lazy) connections to every other peer.
+func NewNode(cfg Config) (*Node, error) {
+	if cfg.Self == "" {
+		return nil, errors.New("cluster: self address is required")
+	}
+	found := false
+	for _, p := range cfg.Peers {
+		if p == cfg.Self {
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("cluster: self address %s is not in the peer list", cfg.Self)
+	}
+	if cfg.TLS == nil && !cfg.Insecure && len(cfg.Peers) > 1 {
+		return nil, errors.New("cluster: peers must authenticate with mutual TLS (server.tls and cluster.peer_ca_file) unless insecure is set")
+	}
+	if cfg.BatchWindow <= 0 {
+		cfg.BatchWindow = time.Millisecond
+	}
+	if cfg.MaxBatch <= 0 {
+		cfg.MaxBatch = 100
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = 50 * time.Millisecond
+	}
+	if cfg.DownBackoff <= 0 {
+		cfg.DownBackoff = time.Second
+	}
+
+	n := &Node{
+		cfg:      cfg,
+		ring:     NewRing(cfg.Peers, cfg.VirtualNodes),
+		peers:    make(map[string]*peer),
+		policies: make(map[string]limiter.Limiter),
+	}
+	creds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		creds = credentials.NewTLS(cfg.TLS)
+	}
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(codecName)),
+	}, cfg.DialOptions...)
+	for _, addr := range cfg.Peers {
+		if addr == cfg.Self {
+			continue
+		}
+		conn, err := grpc.Dial(addr, dialOpts...)
+		if err != nil {
+			_ = n.Close()
+			return nil, fmt.Errorf("cluster: dial %s: %w", addr, err)
+		}
+		n.peers[addr] = newPeer(addr, conn, cfg)
+	}
+	return n, nil
+}
+
+// Wrap registers local as the limiter for policy and returns a limiter that
+// routes each key to its owner.
+func (n *Node) Wrap(policy string, local limiter.Limiter) limiter.Limiter {
+	n.mu.Lock()
+	n.policies[policy] = local
+	n.mu.Unlock()
+	return &routedLimiter{node: n, policy: policy, local: local}
+}
+
+// Register exposes the peer service on s.
+func (n *Node) Register(s *grpc.Server) {
+	s.RegisterService(&serviceDesc, n)
+}
+
+// Owner returns the peer responsible for key.
+func (n *Node) Owner(key string) string {
+	return n.ring.Owner(key)
+}
+
+// Close releases peer connections.
+func (n *Node) Close() error {
+	var firstErr error
+	for _, p := range n.peers {
+		if err := p.conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// AllowBatch evaluates forwarded calls against the local limiters.
+func (n *Node) AllowBatch(ctx context.Context, req *batchRequest) (*batchResponse, error) {
+	resp := &batchResponse{Results: make([]batchResult, len(req.Items))}
+	for i, item := range req.Items {
+		n.mu.RLock()
+		local, ok := n.policies[item.Policy]
+		n.mu.RUnlock()
+		if !ok {
+			resp.Results[i] = batchResult{Error: "unknown policy " + item.Policy}
+			continue
+		}
+		result, err := local.Allow(ctx, item.Key)
+		if err != nil {
+			resp.Results[i] = batchResult{Error: err.Error()}
+			continue
+		}
+		resp.Results[i] = toBatchResult(result)
+	}
+	return resp, nil
+}
+
+// KeyOp applies a forwarded operator action to the local limiter, which holds
+// the key's state because this node owns it.
+func (n *Node) KeyOp(ctx context.Context, req *keyOpRequest) (*keyOpResponse, error) {
+	resp, err := n.applyKeyOp(ctx, req)
+	if err != nil {
+		return &keyOpResponse{Error: err.Error()}, nil
+	}
+	return resp, nil
+}
+
+func (n *Node) applyKeyOp(ctx context.Context, req *keyOpRequest) (*keyOpResponse, error) {
+	n.mu.RLock()
+	local, ok := n.policies[req.Policy]
+	n.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", limiter.ErrUnknownPolicy, req.Policy)
+	}
+	inspector, ok := local.(limiter.Inspector)
+	if !ok {
+		return nil, limiter.ErrNotInspectable
+	}
+	switch req.Op {
+	case opInspect:
+		state, err := inspector.Inspect(ctx, req.Key)
+		if err != nil {
+			return nil, err
+		}
+		return &keyOpResponse{State: &state}, nil
+	case opReset:
+		return &keyOpResponse{}, inspector.Reset(ctx, req.Key)
+	case opGrant:
+		return &keyOpResponse{}, inspector.Grant(ctx, req.Key, req.N)
+	default:
+		return nil, fmt.Errorf("unknown key operation %q", req.Op)
+	}
+}
+
+type routedLimiter struct {
+	node   *Node
+	policy string
+	local  limiter.Limiter
+}
+
+// Allow evaluates key on its owner, or locally if the owner is this node or unreachable.
+func (r *routedLimiter) Allow(ctx context.Context, key string) (limiter.Result, error) {
+	owner := r.node.ring.Owner(key)
+	p, remote := r.node.peers[owner]
+	if !remote || p.isDown() {
+		return r.local.Allow(ctx, key)
+	}
+	result, err := p.submit(ctx, batchItem{Policy: r.policy, Key: key})
+	if err != nil {
+		if ctx.Err() != nil {
+			return limiter.Result{}, ctx.Err()
+		}
+		return r.local.Allow(ctx, key)
+	}
+	return result, nil
+}
+
+// Inspect, Reset and Grant act on the key's owner, which holds the state its
+// requests are evaluated against. Unlike Allow they do not fall back to this
+// node's copy: an unreachable owner is reported as an error.
+func (r *routedLimiter) Inspect(ctx context.Context, key string) (limiter.KeyState, error) {
+	resp, err := r.keyOp(ctx, keyOpRequest{Policy: r.policy, Key: key, Op: opInspect})
+	if err != nil {
+		return limiter.KeyState{}, err
+	}
+	if resp.State == nil {
+		return limiter.KeyState{}, fmt.Errorf("cluster: owner of key did not return its state")
+	}
+	return *resp.State, nil
+}
+
+func (r *routedLimiter) Reset(ctx context.Context, key string) error {
+	_, err := r.keyOp(ctx, keyOpRequest{Policy: r.policy, Key: key, Op: opReset})
+	return err
+}
+
+func (r *routedLimiter) Grant(ctx context.Context, key string, n int) error {
+	_, err := r.keyOp(ctx, keyOpRequest{Policy: r.policy, Key: key, Op: opGrant, N: n})
+	return err
+}
+
+// keyOp runs req on the key's owner, locally when that is this node.
+func (r *routedLimiter) keyOp(ctx context.Context, req keyOpRequest) (*keyOpResponse, error) {
+	owner := r.node.ring.Owner(req.Key)
+	p, remote := r.node.peers[owner]
+	if !remote {
+		return r.node.applyKeyOp(ctx, &req)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.RequestTimeout)
+	defer cancel()
+	resp := new(keyOpResponse)
+	if err := p.conn.Invoke(ctx, keyOpRoute, &req, resp); err != nil {
+		return nil, fmt.Errorf("cluster: key is owned by %s: %w", owner, err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("cluster: key is owned by %s: %s", owner, resp.Error)
+	}
+	return resp, nil
+}
+
+// Close releases the local limiter's background resources, if any.
+func (r *routedLimiter) Close() error {
+	if closer, ok := r.local.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+type call struct {
+	item batchItem
+	done chan outcome
+}
+
+type outcome struct {
+	result limiter.Result
+	err    error
+}
+
+// peer batches calls destined for one remote node.
+type peer struct {
+	addr string
+	conn *grpc.ClientConn
+	cfg  Config
+
+	mu        sync.Mutex
+	pending   []*call
+	timer     *time.Timer
+	downUntil time.Time
+}
+
+func newPeer(addr string, conn *grpc.ClientConn, cfg Config) *peer {
+	return &peer{addr: addr, conn: conn, cfg: cfg}
+}
+
+func (p *peer) isDown() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return time.Now().Before(p.downUntil)
+}
+
+func (p *peer) submit(ctx context.Context, item batchItem) (limiter.Result, error) {
+	c := &call{item: item, done: make(chan outcome, 1)}
+
+	p.mu.Lock()
+	p.pending = append(p.pending, c)
+	var batch []*call
+	if len(p.pending) >= p.cfg.MaxBatch {
+		batch = p.takeLocked()
+	} else if p.timer == nil {
+		p.timer = time.AfterFunc(p.cfg.BatchWindow, p.flush)
+	}
+	p.mu.Unlock()
+	if batch != nil {
+		go p.send(batch)
+	}
+
+	select {
+	case out := <-c.done:
+		return out.result, out.err
+	case <-ctx.Done():
+		return limiter.Result{}, ctx.Err()
+	}
+}
+
+func (p *peer) flush() {
+	p.mu.Lock()
+	batch := p.takeLocked()
+	p.mu.Unlock()
+	if len(batch) > 0 {
+		p.send(batch)
+	}
+}
+
+// takeLocked must be called with mu held.
+func (p *peer) takeLocked() []*call {
+	batch := p.pending
+	p.pending = nil
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	return batch
+}
+
+func (p *peer) send(batch []*call) {
+	req := &batchRequest{Items: make([]batchItem, len(batch))}
+	for i, c := range batch {
+		req.Items[i] = c.item
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.RequestTimeout)
+	defer cancel()
+	resp := new(batchResponse)
+	err := p.conn.Invoke(ctx, allowBatchRoute, req, resp)
+	if err == nil && len(resp.Results) != len(batch) {
+		err = fmt.Errorf("cluster: peer %s answered %d of %d calls", p.addr, len(resp.Results), len(batch))
+	}
+	if err != nil {
+		p.mu.Lock()
+		p.downUntil = time.Now().Add(p.cfg.DownBackoff)
+		p.mu.Unlock()
+		for _, c := range batch {
+			c.done <- outcome{err: err}
+		}
+		return
+	}
+
+	for i, c := range batch {
+		res := resp.Results[i]
+		if res.Error != "" {
+			c.done <- outcome{err: fmt.Errorf("cluster: peer %s: %s", p.addr, res.Error)}
+			continue
+		}
+		c.done <- outcome{result: res.result()}
+	}
+}
@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// The peer protocol is a unary RPC carrying a batch of Allow calls, plus one
+// forwarding operator actions (inspect, reset, grant) to a key's owner.
+// Messages are plain Go structs encoded as JSON via a registered gRPC codec,
+// so no generated code is needed. The codec has a package-specific name and
+// is selected per call, leaving any other "json" codec in the binary alone.
+const (
+	serviceName     = "ratelimiter.cluster.v1.Peer"
+	allowBatchRoute = "/" + serviceName + "/AllowBatch"
+	keyOpRoute      = "/" + serviceName + "/KeyOp"
+	codecName       = "ratelimiter-cluster-json"
+)
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return codecName }
+
+type batchItem struct {
+	Policy string `json:"policy"`
+	Key    string `json:"key"`
+}
+
+type batchRequest struct {
+	Items []batchItem `json:"items"`
+}
+
+type batchResult struct {
+	Allowed    bool          `json:"allowed"`
+	Remaining  int           `json:"remaining"`
+	Limit      int           `json:"limit"`
+	RetryAfter time.Duration `json:"retry_after"`
+	ResetAfter time.Duration `json:"reset_after"`
+	Window     time.Duration `json:"window"`
+	Error      string        `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+func toBatchResult(r limiter.Result) batchResult {
+	return batchResult{
+		Allowed:    r.Allowed,
+		Remaining:  r.Remaining,
+		Limit:      r.Limit,
+		RetryAfter: r.RetryAfter,
+		ResetAfter: r.ResetAfter,
+		Window:     r.Window,
+	}
+}
+
+func (b batchResult) result() limiter.Result {
+	return limiter.Result{
+		Allowed:    b.Allowed,
+		Remaining:  b.Remaining,
+		Limit:      b.Limit,
+		RetryAfter: b.RetryAfter,
+		ResetAfter: b.ResetAfter,
+		Window:     b.Window,
+	}
+}
+
+// Operator actions carried by keyOpRequest.
+const (
+	opInspect = "inspect"
+	opReset   = "reset"
+	opGrant   = "grant"
+)
+
+type keyOpRequest struct {
+	Policy string `json:"policy"`
+	Key    string `json:"key"`
+	Op     string `json:"op"`
+	N      int    `json:"n,omitempty"`
+}
+
+type keyOpResponse struct {
+	// State is set for inspect. Its algorithm state arrives as a JSON object.
+	State *limiter.KeyState `json:"state,omitempty"`
+	Error string            `json:"error,omitempty"`
+}
+
+type peerServer interface {
+	AllowBatch(ctx context.Context, req *batchRequest) (*batchResponse, error)
+	KeyOp(ctx context.Context, req *keyOpRequest) (*keyOpResponse, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*peerServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "AllowBatch",
+		Handler:    allowBatchHandler,
+	}, {
+		MethodName: "KeyOp",
+		Handler:    keyOpHandler,
+	}},
+	Metadata: "cluster",
+}
+
+func allowBatchHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(batchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(peerServer).AllowBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: allowBatchRoute}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(peerServer).AllowBatch(ctx, req.(*batchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func keyOpHandler(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+	in := new(keyOpRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(peerServer).KeyOp(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: keyOpRoute}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return srv.(peerServer).KeyOp(ctx, req.(*keyOpRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
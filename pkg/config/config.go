@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"time"
@@ -10,10 +12,123 @@ import (
 
 // Config is the top-level configuration file.
 type Config struct {
-	Server   ServerConfig  `yaml:"server"`
-	Metrics  MetricsConfig `yaml:"metrics"`
-	Storage  StorageConfig `yaml:"storage"`
-	Policies []Policy      `yaml:"policies"`
+	Server    ServerConfig    `yaml:"server"`
+	Metrics   MetricsConfig   `yaml:"metrics"`
+	Storage   StorageConfig   `yaml:"storage"`
+	Admin     AdminConfig     `yaml:"admin"`
+	Cluster   ClusterConfig   `yaml:"cluster"`
+	Overrides OverridesConfig `yaml:"overrides"`
+	Tracing   TracingConfig   `yaml:"tracing"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	Events    EventsConfig    `yaml:"events"`
+	Policies  []Policy        `yaml:"policies"`
+
+	// hash identifies the loaded file contents.
+	hash string
+}
+
+// Hash returns a short SHA-256 of the file the configuration was loaded from.
+func (c *Config) Hash() string {
+	return c.hash
+}
+
+// EventsConfig publishes limit events to webhooks and a live stream.
+type EventsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Buffer is the number of events queued per sink (default 1024); events
+	// are dropped when a sink falls further behind.
+	Buffer int `yaml:"buffer"`
+	// Thresholds publish threshold_crossed when a key has consumed the given
+	// fraction of its quota, e.g. 0.8.
+	Thresholds []float64       `yaml:"thresholds"`
+	Webhooks   []WebhookConfig `yaml:"webhooks"`
+	// Stream serves live events at GET /admin/events; requires the admin api.
+	Stream bool `yaml:"stream"`
+}
+
+// WebhookConfig delivers batches of events to an HTTP endpoint.
+type WebhookConfig struct {
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	// Types restricts the delivered event types; all when empty.
+	Types         []string `yaml:"types"`
+	BatchSize     int      `yaml:"batch_size"`
+	FlushInterval Duration `yaml:"flush_interval"`
+	// MaxRetries is the number of retries per batch (default 3); negative disables them.
+	MaxRetries   int      `yaml:"max_retries"`
+	RetryBackoff Duration `yaml:"retry_backoff"`
+	Timeout      Duration `yaml:"timeout"`
+}
+
+// LoggingConfig configures the structured server log.
+type LoggingConfig struct {
+	// Level is debug, info (default), warn or error.
+	Level string `yaml:"level"`
+	// Format is text (default) or json.
+	Format    string            `yaml:"format"`
+	Decisions DecisionLogConfig `yaml:"decisions"`
+	// KeyHashSecret keys the HMAC that replaces client identities in logs;
+	// LOG_KEY_HASH_SECRET is used when empty, and a random per-process secret
+	// when both are.
+	KeyHashSecret string `yaml:"key_hash_secret"`
+}
+
+// DecisionLogConfig samples per-decision access log entries.
+type DecisionLogConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// AllowedSampleRate is the fraction of allowed decisions logged
+	// (default 0.01); negative logs none.
+	AllowedSampleRate float64 `yaml:"allowed_sample_rate"`
+	// DeniedSampleRate is the fraction of denied decisions logged
+	// (default 1); negative logs none.
+	DeniedSampleRate float64 `yaml:"denied_sample_rate"`
+}
+
+// TracingConfig exports OpenTelemetry spans for requests and limiter decisions.
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Exporter is otlp (OTLP over HTTP, default) or stdout.
+	Exporter string `yaml:"exporter"`
+	// Endpoint is the OTLP collector host:port (default localhost:4318).
+	Endpoint string `yaml:"endpoint"`
+	Insecure bool   `yaml:"insecure"`
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string `yaml:"service_name"`
+	// SampleRatio is the fraction of new traces recorded (default 1); sampled
+	// parents are always followed.
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// OverridesConfig tunes runtime per-key overrides managed via the admin API.
+type OverridesConfig struct {
+	// RefreshInterval is how often overrides are reloaded from storage.
+	RefreshInterval Duration `yaml:"refresh_interval"`
+}
+
+// ClusterConfig shares limiter state between replicas without external storage.
+type ClusterConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// ListenAddress serves the peer protocol.
+	ListenAddress string `yaml:"listen_address"`
+	// AdvertiseAddress is this replica's entry in Peers.
+	AdvertiseAddress string   `yaml:"advertise_address"`
+	Peers            []string `yaml:"peers"`
+	BatchWindow      Duration `yaml:"batch_window"`
+	MaxBatch         int      `yaml:"max_batch"`
+	RequestTimeout   Duration `yaml:"request_timeout"`
+	// PeerCAFile issues the certificates peers authenticate each other with.
+	// A cluster with more than one member requires it and server.tls unless
+	// Insecure is set. It must not be the CA of API client certificates.
+	PeerCAFile string `yaml:"peer_ca_file"`
+	// Insecure allows plaintext, unauthenticated peers.
+	Insecure bool `yaml:"insecure"`
+}
+
+// AdminConfig exposes the authenticated admin API under /admin/.
+type AdminConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Token is the bearer token; ADMIN_TOKEN is used when empty.
+	Token string `yaml:"token"`
 }
 
 // ServerConfig configures the HTTP listener.
@@ -23,6 +138,66 @@ type ServerConfig struct {
 	ReadTimeout  Duration `yaml:"read_timeout"`
 	WriteTimeout Duration `yaml:"write_timeout"`
 	IdleTimeout  Duration `yaml:"idle_timeout"`
+	// RateLimitHeaders selects legacy, ietf or both header sets.
+	RateLimitHeaders string          `yaml:"rate_limit_headers"`
+	Responses        ResponsesConfig `yaml:"responses"`
+	Readiness        ReadinessConfig `yaml:"readiness"`
+	// TLS applies to both the HTTP and gRPC listeners.
+	TLS ServerTLSConfig `yaml:"tls"`
+}
+
+// ServerTLSConfig serves HTTPS and gRPC over TLS, optionally requiring client
+// certificates (mTLS) on the API and admin routes. Files are reloaded when
+// they change.
+type ServerTLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientCAFile verifies client certificates; setting it enables mTLS.
+	ClientCAFile string `yaml:"client_ca_file"`
+	// ClientAuth is none, request, verify_if_given or require and applies to
+	// the whole listener. Left empty with ClientCAFile set, certificates are
+	// verified when given and required only on /api/, /admin/ and gRPC calls
+	// other than health, so probes and metrics scrapes work without one.
+	ClientAuth string `yaml:"client_auth"`
+	// MinVersion is 1.2 (default) or 1.3.
+	MinVersion string `yaml:"min_version"`
+	// ReloadInterval is how often the files are checked for changes (default 30s).
+	ReloadInterval Duration `yaml:"reload_interval"`
+}
+
+// ReadinessConfig tunes /readyz and the gRPC health status.
+type ReadinessConfig struct {
+	// Timeout bounds the storage ping (default 1s).
+	Timeout Duration `yaml:"timeout"`
+	// Interval is how often the gRPC health status is refreshed (default 5s).
+	Interval Duration `yaml:"interval"`
+	// DrainDelay keeps serving while reporting not ready after a shutdown
+	// signal, giving load balancers time to stop routing.
+	DrainDelay Duration `yaml:"drain_delay"`
+}
+
+// ResponsesConfig customizes the responses sent when a request is rejected.
+type ResponsesConfig struct {
+	RateLimited  ResponseConfig `yaml:"rate_limited"`
+	Denied       ResponseConfig `yaml:"denied"`
+	StorageError ResponseConfig `yaml:"storage_error"`
+}
+
+// ResponseConfig describes a rejection response. Body is a text/template.
+type ResponseConfig struct {
+	Status      int    `yaml:"status"`
+	ContentType string `yaml:"content_type"`
+	Body        string `yaml:"body"`
+	// GRPCCode and GRPCMessage shape the status returned to gRPC callers,
+	// e.g. grpc_code: UNAVAILABLE.
+	GRPCCode    string `yaml:"grpc_code"`
+	GRPCMessage string `yaml:"grpc_message"`
+}
+
+// IsZero reports whether no field has been configured.
+func (r ResponseConfig) IsZero() bool {
+	return r == ResponseConfig{}
 }
 
 // GRPCAddress returns the configured gRPC listener.
@@ -34,20 +209,108 @@ func (s ServerConfig) GRPCAddress() string {
 type MetricsConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Path    string `yaml:"path"`
+	// ActiveKeysInterval is how often active keys are counted per policy by
+	// scanning storage. Negative disables the count.
+	ActiveKeysInterval Duration `yaml:"active_keys_interval"`
+	// WatchedKeys have their remaining quota exported every WatchInterval.
+	WatchedKeys   []WatchedKey       `yaml:"watched_keys"`
+	WatchInterval Duration           `yaml:"watch_interval"`
+	HeavyHitters  HeavyHittersConfig `yaml:"heavy_hitters"`
+}
+
+// HeavyHittersConfig tracks the most active and most limited keys per policy.
+type HeavyHittersConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Capacity is the number of keys tracked per policy and kind (default 100).
+	Capacity int `yaml:"capacity"`
+	// ExportTop exports this many keys per policy and kind as a metric; 0 disables it.
+	ExportTop int `yaml:"export_top"`
+	// HalfLife halves every count at this interval, so the lists follow
+	// current traffic (default 5m).
+	HalfLife Duration `yaml:"half_life"`
+}
+
+// WatchedKey names one identity under one policy.
+type WatchedKey struct {
+	Policy string `yaml:"policy"`
+	Key    string `yaml:"key"`
 }
 
 // StorageConfig describes the storage driver.
 type StorageConfig struct {
-	Driver string      `yaml:"driver"`
-	Redis  RedisConfig `yaml:"redis"`
+	Driver     string           `yaml:"driver"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Memory     MemoryConfig     `yaml:"memory"`
+	File       FileConfig       `yaml:"file"`
+	Resilience ResilienceConfig `yaml:"resilience"`
+	// StateEncoding is binary (default) or json, the pre-binary format.
+	StateEncoding string `yaml:"state_encoding"`
+}
+
+// MemoryConfig bounds the in-memory driver.
+type MemoryConfig struct {
+	SweepInterval Duration `yaml:"sweep_interval"`
+	MaxEntries    int      `yaml:"max_entries"`
+	// Eviction is lru (default) or random.
+	Eviction string `yaml:"eviction"`
+	// Shards is the number of lock partitions (0 = 4 x GOMAXPROCS).
+	Shards int `yaml:"shards"`
+	// SnapshotPath, when set, persists state across graceful restarts.
+	SnapshotPath string `yaml:"snapshot_path"`
+	// SnapshotMaxBytes caps snapshots uploaded to the admin API (default 64 MiB).
+	SnapshotMaxBytes int64 `yaml:"snapshot_max_bytes"`
+}
+
+// FileConfig configures the persistent single-node driver.
+type FileConfig struct {
+	Path string `yaml:"path"`
+	// SyncInterval batches fsyncs; 0 syncs every write.
+	SyncInterval    Duration `yaml:"sync_interval"`
+	CompactInterval Duration `yaml:"compact_interval"`
+}
+
+// ResilienceConfig wraps remote storage with deadlines and a circuit breaker.
+type ResilienceConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	Timeout          Duration `yaml:"timeout"`
+	FailureThreshold int      `yaml:"failure_threshold"`
+	OpenTimeout      Duration `yaml:"open_timeout"`
+	// Resync is discard (default) or write_back.
+	Resync string `yaml:"resync"`
+	// FallbackMaxEntries caps the local state kept while the circuit is open.
+	FallbackMaxEntries int `yaml:"fallback_max_entries"`
 }
 
 // RedisConfig holds redis specific settings.
 type RedisConfig struct {
+	// Mode is standalone (default), sentinel or cluster.
+	Mode     string `yaml:"mode"`
 	Address  string `yaml:"address"`
 	Username string `yaml:"username"`
 	Password string `yaml:"password"`
 	DB       int    `yaml:"db"`
+	// Addresses lists sentinel addresses (sentinel) or seed nodes (cluster).
+	Addresses        []string `yaml:"addresses"`
+	MasterName       string   `yaml:"master_name"`
+	SentinelUsername string   `yaml:"sentinel_username"`
+	SentinelPassword string   `yaml:"sentinel_password"`
+
+	PoolSize     int            `yaml:"pool_size"`
+	MinIdleConns int            `yaml:"min_idle_conns"`
+	DialTimeout  Duration       `yaml:"dial_timeout"`
+	ReadTimeout  Duration       `yaml:"read_timeout"`
+	WriteTimeout Duration       `yaml:"write_timeout"`
+	TLS          RedisTLSConfig `yaml:"tls"`
+}
+
+// RedisTLSConfig enables TLS towards Redis.
+type RedisTLSConfig struct {
+	Enabled            bool   `yaml:"enabled"`
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
 }
 
 // Policy binds a limiter to routes/methods.
@@ -57,13 +320,55 @@ type Policy struct {
 	Methods   []string        `yaml:"methods"`
 	Identity  IdentityConfig  `yaml:"identity"`
 	Algorithm AlgorithmConfig `yaml:"algorithm"`
+	Headers   string          `yaml:"headers"`
+	Response  ResponseConfig  `yaml:"response"`
+	// Mode is enforce (default) or shadow, which only records would-be rejections.
+	Mode string `yaml:"mode"`
+	// FailureMode is one of closed (default), open or fallback.
+	FailureMode string `yaml:"failure_mode"`
+	// FallbackScale scales limits of the local fallback limiter (default 0.5).
+	FallbackScale float64          `yaml:"fallback_scale"`
+	LocalCache    LocalCacheConfig `yaml:"local_cache"`
+	Penalty       PenaltyConfig    `yaml:"penalty"`
+}
+
+// PenaltyConfig bans keys rejected Threshold times within Window. Bans start
+// at BanDuration and double per repeat offence up to MaxBanDuration.
+type PenaltyConfig struct {
+	// Threshold enables the penalty box when > 0.
+	Threshold      int      `yaml:"threshold"`
+	Window         Duration `yaml:"window"`
+	BanDuration    Duration `yaml:"ban_duration"`
+	MaxBanDuration Duration `yaml:"max_ban_duration"`
+	// Decay forgets earlier bans after this long without a new one.
+	Decay Duration `yaml:"decay"`
+}
+
+// LocalCacheConfig admits requests from an in-process view of hot keys and
+// syncs with storage asynchronously.
+type LocalCacheConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	SyncInterval Duration `yaml:"sync_interval"`
+	// MaxLocal bounds requests admitted per key between syncs.
+	MaxLocal int `yaml:"max_local"`
 }
 
 // IdentityConfig defines how to extract an identity key.
 type IdentityConfig struct {
-	Type     string `yaml:"type"`
+	// Type is ip, header/api_key, query, client_cert or jwt.
+	Type string `yaml:"type"`
+	// Key names the header, query parameter, certificate field (cn, subject,
+	// dns, uri, email) or JWT claim (default sub) used as identity.
 	Key      string `yaml:"key"`
 	Fallback string `yaml:"fallback"`
+	// JWKSFile holds the keys JWT signatures are verified against.
+	JWKSFile string `yaml:"jwks_file"`
+	// Issuer and Audience, when set, must match the JWT's iss and aud.
+	Issuer   string   `yaml:"issuer"`
+	Audience string   `yaml:"audience"`
+	Leeway   Duration `yaml:"leeway"`
+	// AllowMissingExp accepts JWTs without an exp claim (rejected by default).
+	AllowMissingExp bool `yaml:"allow_missing_exp"`
 }
 
 // AlgorithmConfig configures an algorithm instance.
@@ -117,6 +422,8 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 	cfg.setDefaults()
+	sum := sha256.Sum256(bytes)
+	cfg.hash = hex.EncodeToString(sum[:6])
 	return &cfg, nil
 }
 
@@ -136,10 +443,94 @@ func (c *Config) setDefaults() {
 	if c.Server.IdleTimeout.Duration() == 0 {
 		c.Server.IdleTimeout = Duration(60 * time.Second)
 	}
+	if c.Server.Readiness.Timeout <= 0 {
+		c.Server.Readiness.Timeout = Duration(time.Second)
+	}
+	if c.Server.Readiness.Interval <= 0 {
+		c.Server.Readiness.Interval = Duration(5 * time.Second)
+	}
+	if c.Server.TLS.ReloadInterval <= 0 {
+		c.Server.TLS.ReloadInterval = Duration(30 * time.Second)
+	}
+	if c.Server.RateLimitHeaders == "" {
+		c.Server.RateLimitHeaders = "legacy"
+	}
 	if c.Metrics.Path == "" {
 		c.Metrics.Path = "/metrics"
 	}
+	if c.Overrides.RefreshInterval <= 0 {
+		c.Overrides.RefreshInterval = Duration(5 * time.Second)
+	}
+	if c.Metrics.WatchInterval <= 0 {
+		c.Metrics.WatchInterval = Duration(15 * time.Second)
+	}
+	if c.Metrics.ActiveKeysInterval == 0 {
+		c.Metrics.ActiveKeysInterval = Duration(30 * time.Second)
+	}
 	if c.Storage.Driver == "" {
 		c.Storage.Driver = "memory"
 	}
+	if c.Logging.Level == "" {
+		c.Logging.Level = "info"
+	}
+	if c.Logging.Format == "" {
+		c.Logging.Format = "text"
+	}
+	if c.Logging.Decisions.AllowedSampleRate == 0 {
+		c.Logging.Decisions.AllowedSampleRate = 0.01
+	}
+	if c.Logging.Decisions.DeniedSampleRate == 0 {
+		c.Logging.Decisions.DeniedSampleRate = 1
+	}
+	if c.Events.Buffer <= 0 {
+		c.Events.Buffer = 1024
+	}
+	for i := range c.Events.Webhooks {
+		hook := &c.Events.Webhooks[i]
+		if hook.BatchSize <= 0 {
+			hook.BatchSize = 100
+		}
+		if hook.FlushInterval <= 0 {
+			hook.FlushInterval = Duration(time.Second)
+		}
+		if hook.MaxRetries == 0 {
+			hook.MaxRetries = 3
+		}
+		if hook.RetryBackoff <= 0 {
+			hook.RetryBackoff = Duration(500 * time.Millisecond)
+		}
+		if hook.Timeout <= 0 {
+			hook.Timeout = Duration(5 * time.Second)
+		}
+	}
+	if c.Tracing.Exporter == "" {
+		c.Tracing.Exporter = "otlp"
+	}
+	if c.Tracing.Endpoint == "" {
+		c.Tracing.Endpoint = "localhost:4318"
+	}
+	if c.Tracing.ServiceName == "" {
+		c.Tracing.ServiceName = "rate-limiter"
+	}
+	if c.Tracing.SampleRatio <= 0 {
+		c.Tracing.SampleRatio = 1
+	}
+	if c.Cluster.ListenAddress == "" {
+		c.Cluster.ListenAddress = ":7946"
+	}
+	if c.Admin.Token == "" {
+		c.Admin.Token = os.Getenv("ADMIN_TOKEN")
+	}
+	if c.Logging.KeyHashSecret == "" {
+		c.Logging.KeyHashSecret = os.Getenv("LOG_KEY_HASH_SECRET")
+	}
+	if c.Storage.File.Path == "" {
+		c.Storage.File.Path = "data/limiter.log"
+	}
+	if c.Storage.Memory.SweepInterval.Duration() == 0 {
+		c.Storage.Memory.SweepInterval = Duration(time.Minute)
+	}
+	if c.Storage.Memory.SnapshotMaxBytes <= 0 {
+		c.Storage.Memory.SnapshotMaxBytes = 64 << 20
+	}
 }
@@ -0,0 +1,173 @@
+// Package events publishes rate limiting events to webhooks and live streams
+// without blocking the request path.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Type names an event kind.
+type Type string
+
+const (
+	// TypeLimitExceeded is published for every rejected request.
+	TypeLimitExceeded Type = "limit_exceeded"
+	// TypeThresholdCrossed is published when a request consumes a configured
+	// fraction of a key's quota.
+	TypeThresholdCrossed Type = "threshold_crossed"
+	// TypeBanned is published when the penalty box bans a key.
+	TypeBanned Type = "banned"
+)
+
+// ParseType validates an event type name.
+func ParseType(value string) (Type, error) {
+	switch Type(value) {
+	case TypeLimitExceeded, TypeThresholdCrossed, TypeBanned:
+		return Type(value), nil
+	default:
+		return "", fmt.Errorf("unknown event type %q", value)
+	}
+}
+
+// Event describes something that happened to one key under one policy.
+type Event struct {
+	Type      Type      `json:"type"`
+	Policy    string    `json:"policy"`
+	Key       string    `json:"key"`
+	Time      time.Time `json:"time"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	// Threshold is the crossed fraction of the quota, for threshold_crossed.
+	Threshold float64 `json:"threshold,omitempty"`
+	// RetryAfterMs is the wait before the key is admitted again; for banned
+	// events it is the ban length.
+	RetryAfterMs int64 `json:"retry_after_ms,omitempty"`
+}
+
+// Sink consumes events until the channel is closed. Once ctx is done the
+// bus no longer waits for it and it should return promptly, giving up on
+// undelivered events.
+type Sink interface {
+	Run(ctx context.Context, events <-chan Event)
+}
+
+type subscription struct {
+	ch    chan Event
+	types map[Type]bool
+	// bulk is how much of ch limit_exceeded events may fill. They are
+	// published for every rejected request, so without a share of their own
+	// a flood would crowd out the rarer banned and threshold_crossed events.
+	bulk int
+}
+
+func (s subscription) wants(t Type) bool {
+	return len(s.types) == 0 || s.types[t]
+}
+
+// Bus fans events out to sinks. Every sink has its own bounded queue, so a
+// slow sink never delays the publisher or other sinks; events that do not fit
+// are dropped and counted. limit_exceeded events may only fill half of a
+// queue, keeping the other half for the other types.
+type Bus struct {
+	mu      sync.RWMutex
+	subs    []subscription
+	closed  bool
+	dropped atomic.Uint64
+	wg      sync.WaitGroup
+	// ctx is handed to sinks and cancelled when Close gives up waiting.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewBus returns a bus without sinks.
+func NewBus() *Bus {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Bus{ctx: ctx, cancel: cancel}
+}
+
+// Attach starts sink with a queue of buffer events. When types are given,
+// only those are delivered to it.
+func (b *Bus) Attach(sink Sink, buffer int, types ...Type) {
+	sub := subscription{ch: make(chan Event, buffer), bulk: max(1, buffer/2)}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.ch)
+		return
+	}
+	b.subs = append(b.subs, sub)
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		sink.Run(b.ctx, sub.ch)
+	}()
+}
+
+// Publish queues event for every interested sink. It never blocks.
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	for _, sub := range b.subs {
+		if !sub.wants(event.Type) {
+			continue
+		}
+		if event.Type == TypeLimitExceeded && len(sub.ch) >= sub.bulk {
+			b.dropped.Add(1)
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.dropped.Add(1)
+		}
+	}
+}
+
+// Dropped returns how many events were discarded because a queue was full.
+func (b *Bus) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// Close stops accepting events and waits until sinks have drained their
+// queues or ctx is done. In the latter case the sinks are cancelled, so
+// pending deliveries and retries stop, and Close returns ctx's error once
+// they have returned.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, sub := range b.subs {
+			close(sub.ch)
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		b.cancel()
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
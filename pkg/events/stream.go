@@ -0,0 +1,140 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// heartbeatInterval keeps idle Server-Sent Events connections open through proxies.
+const heartbeatInterval = 15 * time.Second
+
+// Stream serves events to live HTTP clients as Server-Sent Events. Clients
+// that fall more than the client buffer behind miss events rather than
+// slowing the others down.
+type Stream struct {
+	mu      sync.Mutex
+	clients map[chan Event]struct{}
+	buffer  int
+	closed  bool
+}
+
+// NewStream returns a stream buffering up to buffer events per client.
+func NewStream(buffer int) *Stream {
+	return &Stream{clients: make(map[chan Event]struct{}), buffer: buffer}
+}
+
+// Run forwards events to connected clients until events is closed, then
+// disconnects them. Forwarding never blocks, so ctx is not needed.
+func (s *Stream) Run(_ context.Context, events <-chan Event) {
+	for event := range events {
+		s.mu.Lock()
+		for client := range s.clients {
+			select {
+			case client <- event:
+			default:
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	s.Close()
+}
+
+// Close disconnects every client and refuses new ones, so that a graceful
+// HTTP shutdown does not wait on open streams.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for client := range s.clients {
+		close(client)
+		delete(s.clients, client)
+	}
+}
+
+func (s *Stream) subscribe() (chan Event, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, false
+	}
+	client := make(chan Event, s.buffer)
+	s.clients[client] = struct{}{}
+	return client, true
+}
+
+func (s *Stream) unsubscribe(client chan Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.clients[client]; ok {
+		delete(s.clients, client)
+		close(client)
+	}
+}
+
+// ServeHTTP tails events until the client disconnects. The optional type and
+// policy query parameters restrict which events are sent.
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var typeFilter Type
+	if value := r.URL.Query().Get("type"); value != "" {
+		t, err := ParseType(value)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		typeFilter = t
+	}
+	policyFilter := r.URL.Query().Get("policy")
+
+	client, ok := s.subscribe()
+	if !ok {
+		http.Error(w, "event stream closed", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.unsubscribe(client)
+
+	rc := http.NewResponseController(w)
+	// The stream outlives the server's write timeout.
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, ok := <-client:
+			if !ok {
+				return
+			}
+			if (typeFilter != "" && event.Type != typeFilter) || (policyFilter != "" && event.Policy != policyFilter) {
+				continue
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WebhookConfig describes an HTTP endpoint receiving batches of events.
+type WebhookConfig struct {
+	URL     string
+	Headers map[string]string
+	// BatchSize sends a batch as soon as it holds this many events.
+	BatchSize int
+	// FlushInterval sends a partial batch after this long.
+	FlushInterval time.Duration
+	// MaxRetries is how often a failed delivery is retried, with RetryBackoff
+	// doubling between attempts.
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// Client sends the requests; http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// Webhook POSTs batches of events as {"events": [...]} to a URL. Network
+// errors, 429 and 5xx responses are retried; a batch is dropped once retries
+// are exhausted.
+type Webhook struct {
+	cfg WebhookConfig
+}
+
+// NewWebhook returns a webhook sink for cfg.
+func NewWebhook(cfg WebhookConfig) *Webhook {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	return &Webhook{cfg: cfg}
+}
+
+// Run batches events and delivers them until events is closed, then flushes
+// what is left. Once ctx is done, pending retries stop and the events not
+// yet delivered are dropped with a warning.
+func (w *Webhook) Run(ctx context.Context, events <-chan Event) {
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Event, 0, w.cfg.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		w.deliver(ctx, batch)
+		batch = make([]Event, 0, w.cfg.BatchSize)
+	}
+	for {
+		if ctx.Err() != nil {
+			if pending := len(batch) + len(events); pending > 0 {
+				slog.Warn("dropping webhook events", "url", w.cfg.URL, "events", pending, "error", ctx.Err())
+			}
+			return
+		}
+		select {
+		case event, ok := <-events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= w.cfg.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (w *Webhook) deliver(ctx context.Context, batch []Event) {
+	body, err := json.Marshal(map[string]any{"events": batch})
+	if err != nil {
+		slog.Error("failed to encode webhook events", "url", w.cfg.URL, "error", err)
+		return
+	}
+	backoff := w.cfg.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return
+		}
+		if !retry || attempt >= w.cfg.MaxRetries || ctx.Err() != nil {
+			slog.Warn("dropping webhook events", "url", w.cfg.URL, "events", len(batch), "attempts", attempt+1, "error", err)
+			return
+		}
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Warn("dropping webhook events", "url", w.cfg.URL, "events", len(batch), "attempts", attempt+1, "error", ctx.Err())
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one request, reporting whether a failure is worth retrying.
+func (w *Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range w.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := w.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook responded %s", resp.Status)
+}
@@ -0,0 +1,306 @@
+// Package jwt verifies signed JSON Web Tokens against a local JWKS file.
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256" // SHA-256 for RS256, PS256 and ES256
+	_ "crypto/sha512" // SHA-384 and SHA-512 for the other algorithms
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrInvalidToken is wrapped by every verification failure.
+var ErrInvalidToken = errors.New("invalid token")
+
+// checkInterval bounds how often the JWKS file is checked for changes.
+const checkInterval = 30 * time.Second
+
+// Config describes how tokens are verified.
+type Config struct {
+	// JWKSFile holds the JSON Web Key Set with the signing keys.
+	JWKSFile string
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp and nbf.
+	Leeway time.Duration
+	// AllowMissingExp accepts tokens without an exp claim, which otherwise
+	// are rejected because they would stay valid forever.
+	AllowMissingExp bool
+}
+
+// Claims are the decoded claims of a verified token.
+type Claims map[string]any
+
+// String returns claim name as a string. Numbers keep their JSON form.
+func (c Claims) String(name string) (string, bool) {
+	switch v := c[name].(type) {
+	case string:
+		return v, v != ""
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return fmt.Sprint(v), true
+	default:
+		return "", false
+	}
+}
+
+// Verifier checks token signatures with the keys of a JWKS file. The file is
+// loaded again when it changes, so signing keys can be rotated in place.
+type Verifier struct {
+	cfg Config
+	now func() time.Time
+
+	mu      sync.RWMutex
+	keys    []key
+	modTime time.Time
+	checked time.Time
+}
+
+// NewVerifier loads cfg.JWKSFile.
+func NewVerifier(cfg Config) (*Verifier, error) {
+	if cfg.JWKSFile == "" {
+		return nil, errors.New("jwks_file is required")
+	}
+	v := &Verifier{cfg: cfg, now: time.Now}
+	if err := v.load(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *Verifier) load() error {
+	info, err := os.Stat(v.cfg.JWKSFile)
+	if err != nil {
+		return fmt.Errorf("jwks_file: %w", err)
+	}
+	data, err := os.ReadFile(v.cfg.JWKSFile)
+	if err != nil {
+		return fmt.Errorf("jwks_file: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	v.mu.Lock()
+	v.keys, v.modTime, v.checked = keys, info.ModTime(), v.now()
+	v.mu.Unlock()
+	return nil
+}
+
+// currentKeys returns the key set, reloading the file first if it changed.
+// A file that no longer parses leaves the previous keys in place.
+func (v *Verifier) currentKeys() []key {
+	v.mu.RLock()
+	keys, modTime, stale := v.keys, v.modTime, v.now().Sub(v.checked) >= checkInterval
+	v.mu.RUnlock()
+	if !stale {
+		return keys
+	}
+
+	v.mu.Lock()
+	v.checked = v.now()
+	v.mu.Unlock()
+	if info, err := os.Stat(v.cfg.JWKSFile); err == nil && !info.ModTime().Equal(modTime) {
+		if err := v.load(); err == nil {
+			v.mu.RLock()
+			keys = v.keys
+			v.mu.RUnlock()
+		}
+	}
+	return keys
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks token's signature, expiry and, when configured, issuer and
+// audience, and returns its claims.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	if err := v.verifySignature(h, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) verifySignature(h header, signed, signature []byte) error {
+	matched := false
+	for _, k := range v.currentKeys() {
+		if h.Kid != "" && k.kid != h.Kid {
+			continue
+		}
+		if k.alg != "" && k.alg != h.Alg {
+			continue
+		}
+		ok, applicable := verifyWith(h.Alg, k.public, signed, signature)
+		if !applicable {
+			continue
+		}
+		matched = true
+		if ok {
+			return nil
+		}
+	}
+	if !matched {
+		return fmt.Errorf("%w: no key for alg %q and kid %q", ErrInvalidToken, h.Alg, h.Kid)
+	}
+	return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+}
+
+// verifyWith checks signature with public for alg. applicable is false when
+// alg is unsupported or does not fit the key type, which keeps an RSA key
+// from being used with an EC algorithm and rules out "none" and HMAC.
+func verifyWith(alg string, public crypto.PublicKey, signed, signature []byte) (ok, applicable bool) {
+	if len(alg) < 5 {
+		return false, false
+	}
+	var hash crypto.Hash
+	switch alg[len(alg)-3:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+	digest := func() []byte {
+		h := hash.New()
+		h.Write(signed)
+		return h.Sum(nil)
+	}
+
+	switch pub := public.(type) {
+	case *rsa.PublicKey:
+		switch {
+		case hash != 0 && strings.HasPrefix(alg, "RS"):
+			return rsa.VerifyPKCS1v15(pub, hash, digest(), signature) == nil, true
+		case hash != 0 && strings.HasPrefix(alg, "PS"):
+			opts := &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}
+			return rsa.VerifyPSS(pub, hash, digest(), signature, opts) == nil, true
+		}
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		want := map[string]int{"ES256": 32, "ES384": 48, "ES512": 66}[alg]
+		if want == 0 || want != size {
+			return false, false
+		}
+		if len(signature) != 2*size {
+			return false, true
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(pub, digest(), r, s), true
+	case ed25519.PublicKey:
+		if alg == "EdDSA" {
+			return ed25519.Verify(pub, signed, signature), true
+		}
+	}
+	return false, false
+}
+
+func (v *Verifier) validateClaims(claims Claims) error {
+	now := v.now()
+	exp, hasExp, err := numericClaim(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if !hasExp && !v.cfg.AllowMissingExp {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if hasExp && !now.Before(exp.Add(v.cfg.Leeway)) {
+		return fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	nbf, hasNbf, err := numericClaim(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if hasNbf && now.Add(v.cfg.Leeway).Before(nbf) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+	if v.cfg.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.cfg.Issuer {
+			return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, iss)
+		}
+	}
+	if v.cfg.Audience != "" && !hasAudience(claims["aud"], v.cfg.Audience) {
+		return fmt.Errorf("%w: audience mismatch", ErrInvalidToken)
+	}
+	return nil
+}
+
+// numericClaim reads a NumericDate claim, reporting whether it is present.
+func numericClaim(claims Claims, name string) (time.Time, bool, error) {
+	value, present := claims[name]
+	if !present {
+		return time.Time{}, false, nil
+	}
+	n, ok := value.(json.Number)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%w: %s is not a number", ErrInvalidToken, name)
+	}
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("%w: %s: %v", ErrInvalidToken, name, err)
+	}
+	whole := math.Floor(seconds)
+	return time.Unix(int64(whole), int64((seconds-whole)*float64(time.Second))), true, nil
+}
+
+func hasAudience(aud any, want string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == want
+	case []any:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeSegment(segment string, out any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(out)
+}
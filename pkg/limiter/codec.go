@@ -0,0 +1,175 @@
+package limiter
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// StateEncoding selects how algorithm state is serialized into Storage.
+type StateEncoding string
+
+const (
+	// StateEncodingBinary is the compact versioned binary format (default).
+	StateEncodingBinary StateEncoding = "binary"
+	// StateEncodingJSON is the original JSON format. Keep it while replicas
+	// that cannot read binary state are still running.
+	StateEncodingJSON StateEncoding = "json"
+)
+
+// Binary state layout: magic, version, kind, then a fixed-width big-endian
+// payload. JSON documents always start with '{', so the magic byte tells the
+// two formats apart on read.
+const (
+	stateMagic   byte = 0xB5
+	stateVersion byte = 1
+	headerLen         = 3
+	zeroTime          = math.MinInt64
+)
+
+type stateKind byte
+
+const (
+	kindTokenBucket stateKind = iota + 1
+	kindLeakyBucket
+	kindSlidingWindow
+)
+
+// ErrUnsupportedState is returned for binary state this build cannot decode.
+var ErrUnsupportedState = errors.New("limiter: unsupported state encoding")
+
+// ParseStateEncoding validates a configured encoding. Empty values mean binary.
+func ParseStateEncoding(value string) (StateEncoding, error) {
+	switch StateEncoding(value) {
+	case "", StateEncodingBinary:
+		return StateEncodingBinary, nil
+	case StateEncodingJSON:
+		return StateEncodingJSON, nil
+	default:
+		return "", fmt.Errorf("unsupported state encoding %s", value)
+	}
+}
+
+// StateEncoder is implemented by limiters whose state encoding can be chosen.
+// Both formats are always readable, so switching is safe at any time.
+type StateEncoder interface {
+	SetStateEncoding(encoding StateEncoding)
+}
+
+// binaryState is implemented by algorithm state that has a binary form.
+type binaryState interface {
+	stateKind() stateKind
+	appendPayload(dst []byte) []byte
+	decodePayload(src []byte) error
+}
+
+func isBinaryState(data []byte) bool {
+	return len(data) > 0 && data[0] == stateMagic
+}
+
+func encodeBinaryState(state binaryState) []byte {
+	buf := make([]byte, headerLen, headerLen+32)
+	buf[0], buf[1], buf[2] = stateMagic, stateVersion, byte(state.stateKind())
+	return state.appendPayload(buf)
+}
+
+func decodeBinaryState(data []byte, state binaryState) error {
+	if len(data) < headerLen {
+		return fmt.Errorf("%w: truncated header", ErrUnsupportedState)
+	}
+	if data[1] > stateVersion {
+		return fmt.Errorf("%w: version %d", ErrUnsupportedState, data[1])
+	}
+	if stateKind(data[2]) != state.stateKind() {
+		return fmt.Errorf("%w: kind %d, want %d", ErrUnsupportedState, data[2], state.stateKind())
+	}
+	return state.decodePayload(data[headerLen:])
+}
+
+func appendFloat(dst []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(dst, math.Float64bits(v))
+}
+
+func appendInt(dst []byte, v int64) []byte {
+	return binary.BigEndian.AppendUint64(dst, uint64(v))
+}
+
+func appendTime(dst []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return appendInt(dst, zeroTime)
+	}
+	return appendInt(dst, t.UnixNano())
+}
+
+func readFloat(src []byte, off int) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(src[off:]))
+}
+
+func readInt(src []byte, off int) int64 {
+	return int64(binary.BigEndian.Uint64(src[off:]))
+}
+
+func readTime(src []byte, off int) time.Time {
+	nanos := readInt(src, off)
+	if nanos == zeroTime {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
+
+func checkPayload(src []byte, want int) error {
+	if len(src) < want {
+		return fmt.Errorf("%w: payload is %d bytes, want %d", ErrUnsupportedState, len(src), want)
+	}
+	return nil
+}
+
+func (s *tokenBucketState) stateKind() stateKind { return kindTokenBucket }
+
+func (s *tokenBucketState) appendPayload(dst []byte) []byte {
+	return appendTime(appendFloat(dst, s.Tokens), s.LastRefill)
+}
+
+func (s *tokenBucketState) decodePayload(src []byte) error {
+	if err := checkPayload(src, 16); err != nil {
+		return err
+	}
+	s.Tokens = readFloat(src, 0)
+	s.LastRefill = readTime(src, 8)
+	return nil
+}
+
+func (s *leakyBucketState) stateKind() stateKind { return kindLeakyBucket }
+
+func (s *leakyBucketState) appendPayload(dst []byte) []byte {
+	return appendTime(appendFloat(dst, s.WaterLevel), s.LastLeak)
+}
+
+func (s *leakyBucketState) decodePayload(src []byte) error {
+	if err := checkPayload(src, 16); err != nil {
+		return err
+	}
+	s.WaterLevel = readFloat(src, 0)
+	s.LastLeak = readTime(src, 8)
+	return nil
+}
+
+func (s *slidingWindowState) stateKind() stateKind { return kindSlidingWindow }
+
+func (s *slidingWindowState) appendPayload(dst []byte) []byte {
+	dst = appendInt(dst, int64(s.PrevCount))
+	dst = appendInt(dst, int64(s.CurrCount))
+	return appendTime(dst, s.CurrWindowStart)
+}
+
+func (s *slidingWindowState) decodePayload(src []byte) error {
+	if err := checkPayload(src, 24); err != nil {
+		return err
+	}
+	s.PrevCount = int(readInt(src, 0))
+	s.CurrCount = int(readInt(src, 8))
+	s.CurrWindowStart = readTime(src, 16)
+	return nil
+}
@@ -0,0 +1,66 @@
+package limiter
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DecisionLogger writes a sampled access log entry per limiter decision.
+// Keys are logged as a keyed hash (see KeyHasher) so client identities stay
+// out of logs.
+type DecisionLogger struct {
+	logger      *slog.Logger
+	allowedRate float64
+	deniedRate  float64
+}
+
+// NewDecisionLogger logs allowedRate of allowed and deniedRate of denied
+// decisions to logger. Rates are fractions in [0, 1].
+func NewDecisionLogger(logger *slog.Logger, allowedRate, deniedRate float64) *DecisionLogger {
+	return &DecisionLogger{logger: logger, allowedRate: allowedRate, deniedRate: deniedRate}
+}
+
+// SetDecisionLogger registers a sampled access log for every decision.
+func (m *Manager) SetDecisionLogger(logger *DecisionLogger) {
+	m.decisionLog = logger
+}
+
+func (l *DecisionLogger) log(ctx context.Context, hasher *KeyHasher, policy, key string, result Result, latency time.Duration, err error) {
+	denied := err != nil || !result.Allowed || result.ShadowDenied
+	rate := l.allowedRate
+	if denied {
+		rate = l.deniedRate
+	}
+	if rate <= 0 || (rate < 1 && rand.Float64() >= rate) {
+		return
+	}
+	if !l.logger.Enabled(ctx, slog.LevelInfo) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("policy", policy),
+		slog.String("key_hash", hasher.Hash(key)),
+		slog.Bool("allowed", err == nil && result.Allowed),
+		slog.Int("remaining", result.Remaining),
+		slog.Int("limit", result.Limit),
+		slog.Float64("latency_ms", float64(latency.Microseconds())/1000),
+	}
+	if result.Banned {
+		attrs = append(attrs, slog.Bool("banned", true))
+	}
+	if result.Shadow {
+		attrs = append(attrs, slog.Bool("shadow_denied", result.ShadowDenied))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()))
+	}
+	l.logger.LogAttrs(ctx, slog.LevelInfo, "rate limit decision", attrs...)
+}
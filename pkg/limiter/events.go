@@ -0,0 +1,66 @@
+package limiter
+
+import (
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/events"
+)
+
+// EventPublisher receives limit events. Publish must not block.
+type EventPublisher interface {
+	Publish(event events.Event)
+}
+
+// SetEventPublisher registers a publisher for limit_exceeded, banned and
+// threshold_crossed events. thresholds are fractions of a key's quota, e.g.
+// 0.8 for 80% consumed. Shadow mode policies publish nothing.
+func (m *Manager) SetEventPublisher(publisher EventPublisher, thresholds []float64) {
+	m.events = publisher
+	m.thresholds = thresholds
+}
+
+// publishDecision emits the events caused by one decision of an enforced policy.
+func (m *Manager) publishDecision(policy *Policy, key string, result Result) {
+	if result.Banned {
+		return
+	}
+	event := events.Event{
+		Policy:    policy.Name,
+		Key:       key,
+		Time:      time.Now().UTC(),
+		Limit:     result.Limit,
+		Remaining: result.Remaining,
+	}
+	if !result.Allowed {
+		event.Type = events.TypeLimitExceeded
+		event.RetryAfterMs = result.RetryAfter.Milliseconds()
+		m.events.Publish(event)
+		return
+	}
+	if result.Limit <= 0 {
+		return
+	}
+	consumed := float64(result.Limit - result.Remaining)
+	for _, threshold := range m.thresholds {
+		mark := threshold * float64(result.Limit)
+		if consumed >= mark && consumed-1 < mark {
+			event.Type = events.TypeThresholdCrossed
+			event.Threshold = threshold
+			m.events.Publish(event)
+		}
+	}
+}
+
+// publishBan emits a banned event for a ban that just started.
+func (m *Manager) publishBan(policy *Policy, key string, ban time.Duration) {
+	if m.events == nil || policy.Mode == PolicyModeShadow {
+		return
+	}
+	m.events.Publish(events.Event{
+		Type:         events.TypeBanned,
+		Policy:       policy.Name,
+		Key:          key,
+		Time:         time.Now().UTC(),
+		RetryAfterMs: ban.Milliseconds(),
+	})
+}
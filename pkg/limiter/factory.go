@@ -11,9 +11,37 @@ import (
 	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
 )
 
+// Option customizes the Manager built by NewManagerFromConfig.
+type Option func(*factoryOptions)
+
+type factoryOptions struct {
+	encoding StateEncoding
+}
+
+// WithStateEncoding selects the format used to write algorithm state.
+func WithStateEncoding(encoding StateEncoding) Option {
+	return func(o *factoryOptions) {
+		o.encoding = encoding
+	}
+}
+
 // NewManagerFromConfig converts config policies into a Manager backed by the supplied store.
-func NewManagerFromConfig(policies []config.Policy, store storage.Storage) (*Manager, error) {
+func NewManagerFromConfig(policies []config.Policy, store storage.Storage, opts ...Option) (*Manager, error) {
+	o := factoryOptions{encoding: StateEncodingBinary}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	manager := NewManager(nil)
 	var parsed []*Policy
+	built := false
+	defer func() {
+		if !built {
+			// Stop background work of the policies built before the failure.
+			_ = NewManager(parsed).Close()
+		}
+	}()
 	for _, policyConfig := range policies {
 		if policyConfig.Algorithm.Type == "" {
 			return nil, fmt.Errorf("policy %s: algorithm.type is required", policyConfig.Name)
@@ -23,24 +51,86 @@ func NewManagerFromConfig(policies []config.Policy, store storage.Storage) (*Man
 			return nil, fmt.Errorf("policy %s: %w", policyConfig.Name, err)
 		}
 
-		instance, err := limiterFromConfig(policyConfig.Algorithm, store, policyConfig.Name)
+		instance, err := limiterFromConfig(policyConfig.Algorithm, store, policyConfig.Name, o.encoding)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", policyConfig.Name, err)
+		}
+		manager.reportResets(instance, policyConfig.Name)
+		if policyConfig.LocalCache.Enabled {
+			consumer, ok := instance.(Consumer)
+			if !ok {
+				return nil, fmt.Errorf("policy %s: algorithm %s does not support local_cache", policyConfig.Name, policyConfig.Algorithm.Type)
+			}
+			instance = NewLocalCacheLimiter(consumer, LocalCacheConfig{
+				SyncInterval: policyConfig.LocalCache.SyncInterval.Duration(),
+				MaxLocal:     policyConfig.LocalCache.MaxLocal,
+			})
+		}
+
+		mode, err := ParsePolicyMode(policyConfig.Mode)
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", policyConfig.Name, err)
+		}
+
+		failureMode, err := ParseFailureMode(policyConfig.FailureMode)
 		if err != nil {
 			return nil, fmt.Errorf("policy %s: %w", policyConfig.Name, err)
 		}
+		var fallback Limiter
+		if failureMode == FailureModeFallback {
+			fallback, err = fallbackLimiter(policyConfig.Algorithm, policyConfig.FallbackScale, policyConfig.Name)
+			if err != nil {
+				return nil, fmt.Errorf("policy %s: %w", policyConfig.Name, err)
+			}
+		}
+
+		var penalty *penaltyBox
+		if p := policyConfig.Penalty; p.Threshold > 0 {
+			penalty = newPenaltyBox(store, PenaltyConfig{
+				Threshold:      p.Threshold,
+				Window:         p.Window.Duration(),
+				BanDuration:    p.BanDuration.Duration(),
+				MaxBanDuration: p.MaxBanDuration.Duration(),
+				Decay:          p.Decay.Duration(),
+			}, policyConfig.Name)
+			penalty.SetStateEncoding(o.encoding)
+		}
 
 		parsed = append(parsed, &Policy{
-			Name:    policyConfig.Name,
-			Routes:  policyConfig.Routes,
-			Methods: policyConfig.Methods,
-			Limiter: instance,
-			KeyFunc: keyFunc,
+			Name:        policyConfig.Name,
+			Routes:      policyConfig.Routes,
+			Methods:     policyConfig.Methods,
+			Limiter:     instance,
+			KeyFunc:     keyFunc,
+			Algorithm:   AlgorithmType(policyConfig.Algorithm.Type),
+			Mode:        mode,
+			FailureMode: failureMode,
+			Fallback:    fallback,
+			statePrefix: statePrefix(AlgorithmType(policyConfig.Algorithm.Type), policyConfig.Name),
+			algorithm:   policyConfig.Algorithm,
+			penalty:     penalty,
 		})
 	}
 
-	return NewManager(parsed), nil
+	built = true
+	manager.policies = parsed
+	manager.store = store
+	manager.encoding = o.encoding
+	return manager, nil
+}
+
+func limiterFromConfig(cfg config.AlgorithmConfig, store storage.Storage, prefix string, encoding StateEncoding) (Limiter, error) {
+	instance, err := newAlgorithm(cfg, store, prefix)
+	if err != nil {
+		return nil, err
+	}
+	if encoder, ok := instance.(StateEncoder); ok {
+		encoder.SetStateEncoding(encoding)
+	}
+	return instance, nil
 }
 
-func limiterFromConfig(cfg config.AlgorithmConfig, store storage.Storage, prefix string) (Limiter, error) {
+func newAlgorithm(cfg config.AlgorithmConfig, store storage.Storage, prefix string) (Limiter, error) {
 	switch AlgorithmType(cfg.Type) {
 	case AlgorithmTokenBucket:
 		if cfg.Burst <= 0 {
@@ -52,7 +142,7 @@ func limiterFromConfig(cfg config.AlgorithmConfig, store storage.Storage, prefix
 		if cfg.RefillRate <= 0 {
 			cfg.RefillRate = cfg.Limit
 		}
-		return NewTokenBucketLimiter(store, cfg.Burst, cfg.RefillRate, cfg.Interval.Duration(), "tb:"+prefix), nil
+		return NewTokenBucketLimiter(store, cfg.Burst, cfg.RefillRate, cfg.Interval.Duration(), statePrefix(AlgorithmTokenBucket, prefix)), nil
 	case AlgorithmLeakyBucket:
 		if cfg.Limit <= 0 {
 			return nil, fmt.Errorf("limit must be > 0")
@@ -60,7 +150,7 @@ func limiterFromConfig(cfg config.AlgorithmConfig, store storage.Storage, prefix
 		if cfg.LeakRate <= 0 {
 			return nil, fmt.Errorf("leak_rate must be > 0")
 		}
-		return NewLeakyBucketLimiter(store, cfg.Limit, cfg.LeakRate, "lb:"+prefix), nil
+		return NewLeakyBucketLimiter(store, cfg.Limit, cfg.LeakRate, statePrefix(AlgorithmLeakyBucket, prefix)), nil
 	case AlgorithmSlidingWindow:
 		if cfg.Limit <= 0 {
 			return nil, fmt.Errorf("limit must be > 0")
@@ -68,12 +158,26 @@ func limiterFromConfig(cfg config.AlgorithmConfig, store storage.Storage, prefix
 		if cfg.Window.Duration() <= 0 {
 			return nil, fmt.Errorf("window must be > 0")
 		}
-		return NewSlidingWindowLimiter(store, cfg.Limit, cfg.Window.Duration(), "sw:"+prefix), nil
+		return NewSlidingWindowLimiter(store, cfg.Limit, cfg.Window.Duration(), statePrefix(AlgorithmSlidingWindow, prefix)), nil
 	default:
 		return nil, fmt.Errorf("unsupported algorithm: %s", cfg.Type)
 	}
 }
 
+// statePrefix namespaces the storage keys of one policy's algorithm state.
+func statePrefix(algorithm AlgorithmType, policy string) string {
+	switch algorithm {
+	case AlgorithmTokenBucket:
+		return "tb:" + policy
+	case AlgorithmLeakyBucket:
+		return "lb:" + policy
+	case AlgorithmSlidingWindow:
+		return "sw:" + policy
+	default:
+		return ""
+	}
+}
+
 func keyFuncFromConfig(identity config.IdentityConfig) (KeyFunc, error) {
 	switch strings.ToLower(identity.Type) {
 	case "", "ip":
@@ -105,6 +209,10 @@ func keyFuncFromConfig(identity config.IdentityConfig) (KeyFunc, error) {
 			}
 			return value
 		}, nil
+	case "client_cert":
+		return clientCertKeyFunc(identity)
+	case "jwt":
+		return jwtKeyFunc(identity)
 	default:
 		return nil, fmt.Errorf("unsupported identity type %s", identity.Type)
 	}
@@ -120,6 +228,12 @@ func extractIP(r *http.Request) string {
 	if xr := r.Header.Get("X-Real-IP"); xr != "" {
 		return xr
 	}
+	return remoteIP(r)
+}
+
+// remoteIP is the address of the connection's peer. Unlike extractIP it
+// ignores forwarding headers, which any client can set.
+func remoteIP(r *http.Request) string {
 	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err == nil && host != "" {
 		return host
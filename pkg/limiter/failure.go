@@ -0,0 +1,162 @@
+package limiter
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+// FailureMode controls how a policy behaves when its limiter returns an error.
+type FailureMode string
+
+const (
+	// FailureModeClosed surfaces the error so the request is rejected.
+	FailureModeClosed FailureMode = "closed"
+	// FailureModeOpen admits the request without rate limiting.
+	FailureModeOpen FailureMode = "open"
+	// FailureModeFallback evaluates a local in-memory limiter with scaled-down limits.
+	FailureModeFallback FailureMode = "fallback"
+
+	defaultFallbackScale = 0.5
+	errorLogInterval     = 10 * time.Second
+
+	// The fallback store only holds state while storage is down, so it is
+	// swept often and capped rather than configured.
+	fallbackSweepInterval = time.Minute
+	fallbackMaxEntries    = 100_000
+)
+
+// ParseFailureMode validates a configured failure mode. Empty values mean closed.
+func ParseFailureMode(value string) (FailureMode, error) {
+	switch FailureMode(strings.ToLower(strings.TrimSpace(value))) {
+	case "", FailureModeClosed:
+		return FailureModeClosed, nil
+	case FailureModeOpen:
+		return FailureModeOpen, nil
+	case FailureModeFallback:
+		return FailureModeFallback, nil
+	default:
+		return "", fmt.Errorf("unsupported failure_mode %s", value)
+	}
+}
+
+// ErrorObserver is notified whenever a policy's limiter fails.
+type ErrorObserver interface {
+	ObserveStorageError(policy string, mode string)
+}
+
+// StateResetObserver is optionally implemented by an ErrorObserver to learn
+// about stored state that could not be decoded, e.g. after a downgrade, and
+// was replaced by a full quota.
+type StateResetObserver interface {
+	ObserveStateReset(policy string)
+}
+
+// observeStateReset reports discarded state of policy.
+func (m *Manager) observeStateReset(policy string, err error) {
+	if observer, ok := m.observer.(StateResetObserver); ok {
+		observer.ObserveStateReset(policy)
+	}
+	if suppressed, ok := m.resetLogger.allow(); ok {
+		slog.Warn("discarded unreadable limiter state", "policy", policy, "error", err, "suppressed", suppressed)
+	}
+}
+
+// reportResets routes l's state resets to the manager.
+func (m *Manager) reportResets(l Limiter, policy string) {
+	if reporter, ok := l.(resetReporter); ok {
+		reporter.setResetHandler(func(_ string, err error) {
+			m.observeStateReset(policy, err)
+		})
+	}
+}
+
+// fallbackLimiter builds the local limiter used by FailureModeFallback.
+func fallbackLimiter(cfg config.AlgorithmConfig, scale float64, prefix string) (Limiter, error) {
+	if scale <= 0 {
+		scale = defaultFallbackScale
+	}
+	if scale > 1 {
+		return nil, fmt.Errorf("fallback_scale must be within (0, 1]")
+	}
+	store := storage.NewMemoryStorageWithConfig(storage.MemoryConfig{
+		SweepInterval: fallbackSweepInterval,
+		MaxEntries:    fallbackMaxEntries,
+	})
+	instance, err := limiterFromConfig(scaleAlgorithm(cfg, scale), store, "fallback:"+prefix, StateEncodingBinary)
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	return &localLimiter{Limiter: instance, store: store}, nil
+}
+
+// localLimiter is a limiter that owns its in-memory store.
+type localLimiter struct {
+	Limiter
+	store *storage.MemoryStorage
+}
+
+// Close stops the store's janitor.
+func (l *localLimiter) Close() error {
+	return l.store.Close()
+}
+
+// scaleAlgorithm multiplies every limit of cfg by scale, keeping at least one request.
+func scaleAlgorithm(cfg config.AlgorithmConfig, scale float64) config.AlgorithmConfig {
+	scaleInt := func(v int) int {
+		if v <= 0 {
+			return v
+		}
+		return int(math.Max(1, math.Floor(float64(v)*scale)))
+	}
+	cfg.Limit = scaleInt(cfg.Limit)
+	cfg.Burst = scaleInt(cfg.Burst)
+	cfg.RefillRate = scaleInt(cfg.RefillRate)
+	cfg.LeakRate *= scale
+	return cfg
+}
+
+// errorLogger logs limiter failures at most once per interval, reporting how
+// many were suppressed in between.
+type errorLogger struct {
+	mu         sync.Mutex
+	interval   time.Duration
+	last       time.Time
+	suppressed int
+	now        func() time.Time
+}
+
+func newErrorLogger(interval time.Duration) *errorLogger {
+	return &errorLogger{interval: interval, now: time.Now}
+}
+
+func (l *errorLogger) log(policy string, mode FailureMode, err error) {
+	suppressed, ok := l.allow()
+	if !ok {
+		return
+	}
+	slog.Warn("limiter failed", "policy", policy, "failure_mode", string(mode), "error", err, "suppressed", suppressed)
+}
+
+// allow reports whether a message may be logged now and how many were
+// suppressed since the last one.
+func (l *errorLogger) allow() (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if !l.last.IsZero() && now.Sub(l.last) < l.interval {
+		l.suppressed++
+		return 0, false
+	}
+	suppressed := l.suppressed
+	l.suppressed = 0
+	l.last = now
+	return suppressed, true
+}
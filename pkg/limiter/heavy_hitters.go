@@ -0,0 +1,221 @@
+package limiter
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HeavyHitter is one of the most frequent keys of a policy. Count may
+// overestimate the true frequency by at most Error.
+type HeavyHitter struct {
+	Key   string
+	Count uint64
+	Error uint64
+}
+
+// PolicyHeavyHitters lists the top keys of one policy, most frequent first.
+type PolicyHeavyHitters struct {
+	Policy  string
+	Active  []HeavyHitter
+	Limited []HeavyHitter
+}
+
+// spaceSaving is the Space-Saving top-K summary: it tracks at most capacity
+// keys, and a new key replaces the least frequent one, inheriting its count
+// as the error bound. Any key more frequent than N/capacity is guaranteed to
+// be tracked.
+type spaceSaving struct {
+	capacity int
+	index    map[string]*hitter
+	heap     hitterHeap
+}
+
+type hitter struct {
+	HeavyHitter
+	pos int
+}
+
+// hitterHeap is a min-heap on Count.
+type hitterHeap []*hitter
+
+func (h hitterHeap) Len() int           { return len(h) }
+func (h hitterHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h hitterHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos, h[j].pos = i, j
+}
+func (h *hitterHeap) Push(x any) {
+	e := x.(*hitter)
+	e.pos = len(*h)
+	*h = append(*h, e)
+}
+func (h *hitterHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
+
+func newSpaceSaving(capacity int) *spaceSaving {
+	return &spaceSaving{
+		capacity: capacity,
+		index:    make(map[string]*hitter, capacity),
+	}
+}
+
+func (s *spaceSaving) add(key string) {
+	if e, ok := s.index[key]; ok {
+		e.Count++
+		heap.Fix(&s.heap, e.pos)
+		return
+	}
+	if len(s.heap) < s.capacity {
+		e := &hitter{HeavyHitter: HeavyHitter{Key: key, Count: 1}}
+		heap.Push(&s.heap, e)
+		s.index[key] = e
+		return
+	}
+	// Replace the least frequent key; its count bounds the newcomer's error.
+	e := s.heap[0]
+	delete(s.index, e.Key)
+	e.Key, e.Error = key, e.Count
+	e.Count++
+	s.index[key] = e
+	heap.Fix(&s.heap, 0)
+}
+
+// decay halves every count and error bound once per elapsed half-life.
+// Halving keeps the order of counts, so the heap stays valid.
+func (s *spaceSaving) decay(halvings uint) {
+	if halvings >= 64 {
+		halvings = 63
+	}
+	for _, e := range s.heap {
+		e.Count >>= halvings
+		e.Error >>= halvings
+	}
+}
+
+func (s *spaceSaving) top(n int) []HeavyHitter {
+	out := make([]HeavyHitter, 0, len(s.heap))
+	for _, e := range s.heap {
+		if e.Count == 0 {
+			// Decayed away; kept only until a new key takes its slot.
+			continue
+		}
+		out = append(out, e.HeavyHitter)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Key < out[j].Key
+	})
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// heavyHitters tracks the most active and most limited keys of every policy
+// in bounded memory. Counts halve every halfLife so the summaries follow
+// current traffic rather than all-time totals. The policy map is built once
+// and only read afterwards, so recording takes no global lock.
+type heavyHitters struct {
+	halfLife time.Duration
+	now      func() time.Time
+	names    []string
+	policies map[string]*policyHitters
+}
+
+type policyHitters struct {
+	mu      sync.Mutex
+	active  *spaceSaving
+	limited *spaceSaving
+	// decayedAt is the last half-life boundary applied to the counts.
+	decayedAt time.Time
+}
+
+func newHeavyHitters(policies []*Policy, capacity int, halfLife time.Duration) *heavyHitters {
+	h := &heavyHitters{
+		halfLife: halfLife,
+		now:      time.Now,
+		policies: make(map[string]*policyHitters, len(policies)),
+	}
+	now := h.now()
+	for _, policy := range policies {
+		if _, ok := h.policies[policy.Name]; ok {
+			continue
+		}
+		h.names = append(h.names, policy.Name)
+		h.policies[policy.Name] = &policyHitters{
+			active:    newSpaceSaving(capacity),
+			limited:   newSpaceSaving(capacity),
+			decayedAt: now,
+		}
+	}
+	sort.Strings(h.names)
+	return h
+}
+
+func (h *heavyHitters) record(policy, key string, limited bool) {
+	p, ok := h.policies[policy]
+	if !ok {
+		return
+	}
+	p.mu.Lock()
+	h.decayLocked(p)
+	p.active.add(key)
+	if limited {
+		p.limited.add(key)
+	}
+	p.mu.Unlock()
+}
+
+// decayLocked applies the half-lives elapsed since the last decay; p.mu must be held.
+func (h *heavyHitters) decayLocked(p *policyHitters) {
+	elapsed := h.now().Sub(p.decayedAt)
+	if elapsed < h.halfLife {
+		return
+	}
+	halvings := elapsed / h.halfLife
+	p.active.decay(uint(halvings))
+	p.limited.decay(uint(halvings))
+	p.decayedAt = p.decayedAt.Add(halvings * h.halfLife)
+}
+
+// EnableHeavyHitters starts tracking the capacity most active and most
+// limited keys per policy, halving their counts every halfLife (default 5m).
+// It must be called before the manager serves traffic.
+func (m *Manager) EnableHeavyHitters(capacity int, halfLife time.Duration) {
+	if capacity <= 0 {
+		capacity = 100
+	}
+	if halfLife <= 0 {
+		halfLife = 5 * time.Minute
+	}
+	m.hitters = newHeavyHitters(m.policies, capacity, halfLife)
+}
+
+// HeavyHitters returns the top n keys of every policy, sorted by policy name.
+// It returns nil when tracking is disabled.
+func (m *Manager) HeavyHitters(n int) []PolicyHeavyHitters {
+	if m.hitters == nil {
+		return nil
+	}
+	out := make([]PolicyHeavyHitters, 0, len(m.hitters.names))
+	for _, name := range m.hitters.names {
+		p := m.hitters.policies[name]
+		p.mu.Lock()
+		m.hitters.decayLocked(p)
+		out = append(out, PolicyHeavyHitters{
+			Policy:  name,
+			Active:  p.active.top(n),
+			Limited: p.limited.top(n),
+		})
+		p.mu.Unlock()
+	}
+	return out
+}
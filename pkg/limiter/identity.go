@@ -0,0 +1,119 @@
+package limiter
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/jwt"
+)
+
+// verifiedFallback validates the fallback of a client_cert or jwt identity.
+// Those identities always fall back to the connection's address: skipping the
+// policy, or trusting X-Forwarded-For, would let anyone bypass it with a
+// missing or forged credential.
+func verifiedFallback(identity config.IdentityConfig) error {
+	switch strings.ToLower(identity.Fallback) {
+	case "", "ip":
+		return nil
+	default:
+		return fmt.Errorf("identity.fallback %q is not supported for %s identities (want ip)", identity.Fallback, identity.Type)
+	}
+}
+
+// clientCertKeyFunc identifies requests by a field of the verified client
+// certificate. Requests without a verified certificate are limited by the
+// connection's address.
+func clientCertKeyFunc(identity config.IdentityConfig) (KeyFunc, error) {
+	if err := verifiedFallback(identity); err != nil {
+		return nil, err
+	}
+	field := strings.ToLower(identity.Key)
+	if field == "" {
+		field = "cn"
+	}
+	switch field {
+	case "cn", "subject", "dns", "uri", "email":
+	default:
+		return nil, fmt.Errorf("identity.key %q is not a certificate field (cn, subject, dns, uri or email)", identity.Key)
+	}
+	return func(r *http.Request) string {
+		if value := certificateIdentity(r, field); value != "" {
+			return value
+		}
+		return remoteIP(r)
+	}, nil
+}
+
+func certificateIdentity(r *http.Request, field string) string {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	switch field {
+	case "cn":
+		return cert.Subject.CommonName
+	case "subject":
+		return cert.Subject.String()
+	case "dns":
+		return first(cert.DNSNames)
+	case "uri":
+		if len(cert.URIs) > 0 {
+			return cert.URIs[0].String()
+		}
+	case "email":
+		return first(cert.EmailAddresses)
+	}
+	return ""
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// jwtKeyFunc identifies requests by a claim of their verified bearer token.
+// Requests with missing, invalid or expired tokens are limited by the
+// connection's address.
+func jwtKeyFunc(identity config.IdentityConfig) (KeyFunc, error) {
+	if err := verifiedFallback(identity); err != nil {
+		return nil, err
+	}
+	verifier, err := jwt.NewVerifier(jwt.Config{
+		JWKSFile:        identity.JWKSFile,
+		Issuer:          identity.Issuer,
+		Audience:        identity.Audience,
+		Leeway:          identity.Leeway.Duration(),
+		AllowMissingExp: identity.AllowMissingExp,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("jwt identity: %w", err)
+	}
+	claim := identity.Key
+	if claim == "" {
+		claim = "sub"
+	}
+	return func(r *http.Request) string {
+		if value := bearerClaim(r, verifier, claim); value != "" {
+			return value
+		}
+		return remoteIP(r)
+	}, nil
+}
+
+func bearerClaim(r *http.Request, verifier *jwt.Verifier, claim string) string {
+	auth := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	claims, err := verifier.Verify(strings.TrimSpace(auth[len(prefix):]))
+	if err != nil {
+		return ""
+	}
+	value, _ := claims.String(claim)
+	return value
+}
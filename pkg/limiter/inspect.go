@@ -0,0 +1,166 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+var (
+	// ErrUnknownPolicy is returned when an operation names a policy that is not configured.
+	ErrUnknownPolicy = errors.New("unknown policy")
+	// ErrNotInspectable is returned when a policy's limiter cannot be inspected or adjusted.
+	ErrNotInspectable = errors.New("limiter does not support inspection")
+)
+
+// KeyState describes one key under a policy.
+type KeyState struct {
+	// StateKey is the storage key holding the algorithm state.
+	StateKey string
+	// Exists reports whether any state was stored; otherwise State is the fresh default.
+	Exists bool
+	// State is the algorithm specific state, brought up to date.
+	State any
+	// Remaining is the quota available right now, before any further request.
+	Remaining int
+	// Result is what the key's next request would get. Inspecting does not consume quota.
+	Result Result
+}
+
+// Inspector is implemented by limiters whose per-key state can be examined
+// and adjusted by operators.
+type Inspector interface {
+	Inspect(ctx context.Context, key string) (KeyState, error)
+	// Reset forgets key's state so its next request starts with full quota.
+	Reset(ctx context.Context, key string) error
+	// Grant adds n requests of extra quota to key. Token and leaky bucket
+	// grants last until they are used up; sliding window grants count
+	// against the current window and fade with it.
+	Grant(ctx context.Context, key string, n int) error
+}
+
+// InspectKey reports the state of key under the named policy.
+func (m *Manager) InspectKey(ctx context.Context, policy, key string) (KeyState, error) {
+	inspector, err := m.inspector(policy, key)
+	if err != nil {
+		return KeyState{}, err
+	}
+	return inspector.Inspect(ctx, key)
+}
+
+// ResetKey clears the state of key under the named policy.
+func (m *Manager) ResetKey(ctx context.Context, policy, key string) error {
+	inspector, err := m.inspector(policy, key)
+	if err != nil {
+		return err
+	}
+	return inspector.Reset(ctx, key)
+}
+
+// GrantQuota gives key n extra requests under the named policy.
+func (m *Manager) GrantQuota(ctx context.Context, policy, key string, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("grant must be > 0")
+	}
+	inspector, err := m.inspector(policy, key)
+	if err != nil {
+		return err
+	}
+	return inspector.Grant(ctx, key, n)
+}
+
+// inspector returns the limiter requests for key are evaluated against: the
+// key's active override if it has one, otherwise the policy's limiter.
+func (m *Manager) inspector(name, key string) (Inspector, error) {
+	policy, ok := m.Policy(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPolicy, name)
+	}
+	instance := policy.Limiter
+	if override := m.overrides.lookup(policy.Name, key); override != nil {
+		instance = override
+	}
+	inspector, ok := instance.(Inspector)
+	if !ok {
+		return nil, fmt.Errorf("policy %s: %w", name, ErrNotInspectable)
+	}
+	return inspector, nil
+}
+
+// ScanKeys returns a batch of identities that have state under the named
+// policy, with the cursor for the next batch (0 when done).
+func (m *Manager) ScanKeys(ctx context.Context, policy string, cursor uint64) ([]string, uint64, error) {
+	p, err := m.scannable(policy)
+	if err != nil {
+		return nil, 0, err
+	}
+	stateKeys, next, err := m.store.Scan(ctx, p.statePrefix+":", cursor)
+	if err != nil {
+		return nil, 0, err
+	}
+	keys := make([]string, len(stateKeys))
+	for i, stateKey := range stateKeys {
+		keys[i] = m.identity(p.statePrefix, stateKey)
+	}
+	return keys, next, nil
+}
+
+// ActiveKeys counts the identities that have state under the named policy.
+func (m *Manager) ActiveKeys(ctx context.Context, policy string) (int, error) {
+	p, err := m.scannable(policy)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	err = storage.ScanAll(ctx, m.store, p.statePrefix+":", func(keys []string) error {
+		count += len(keys)
+		return nil
+	})
+	return count, err
+}
+
+// ResetPolicy resets every key of the named policy and returns how many were reset.
+func (m *Manager) ResetPolicy(ctx context.Context, policy string) (int, error) {
+	p, err := m.scannable(policy)
+	if err != nil {
+		return 0, err
+	}
+	inspector, _ := p.Limiter.(Inspector)
+	count := 0
+	err = storage.ScanAll(ctx, m.store, p.statePrefix+":", func(keys []string) error {
+		for _, stateKey := range keys {
+			var err error
+			if inspector != nil {
+				// Go through the limiter so local caches forget the key too.
+				err = inspector.Reset(ctx, m.identity(p.statePrefix, stateKey))
+			} else {
+				err = m.store.Delete(ctx, stateKey)
+			}
+			if err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+func (m *Manager) scannable(name string) (*Policy, error) {
+	policy, ok := m.Policy(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPolicy, name)
+	}
+	if m.store == nil || policy.statePrefix == "" {
+		return nil, fmt.Errorf("policy %s: %w", name, ErrNotInspectable)
+	}
+	return policy, nil
+}
+
+// identity reverses stateKey for a key stored under prefix.
+func (m *Manager) identity(prefix, stateKey string) string {
+	return storage.HashUntag(m.store, strings.TrimPrefix(stateKey, prefix+":"))
+}
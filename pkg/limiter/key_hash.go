@@ -0,0 +1,48 @@
+package limiter
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// KeyHasher pseudonymizes client identities in logs and metrics with a truncated
+// HMAC-SHA256. Without the secret a hash cannot be reversed by enumerating
+// candidate identities, e.g. every IPv4 address.
+type KeyHasher struct {
+	secret []byte
+}
+
+// NewKeyHasher hashes with secret. An empty secret is replaced by a random
+// one, so hashes are only comparable within one process; share a secret to
+// correlate clients across replicas and restarts.
+func NewKeyHasher(secret []byte) *KeyHasher {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			panic("limiter: reading random key hash secret: " + err.Error())
+		}
+	}
+	return &KeyHasher{secret: secret}
+}
+
+// Hash returns the first 8 bytes of HMAC-SHA256(secret, key) in hex.
+func (h *KeyHasher) Hash(key string) string {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(key))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// SetKeyHasher replaces the hasher used for client identities in logs.
+func (m *Manager) SetKeyHasher(hasher *KeyHasher) {
+	if hasher != nil {
+		m.keyHasher = hasher
+	}
+}
+
+// KeyHasher returns the hasher used for client identities, so exporters
+// outside the manager publish the same pseudonyms as its logs.
+func (m *Manager) KeyHasher() *KeyHasher {
+	return m.keyHasher
+}
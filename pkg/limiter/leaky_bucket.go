@@ -21,6 +21,7 @@ type LeakyBucketLimiter struct {
 	keyPrefix string
 	ttl       time.Duration
 	now       func() time.Time
+	stateCodec
 }
 
 // NewLeakyBucketLimiter returns a limiter that leaks requests over time.
@@ -38,27 +39,25 @@ func NewLeakyBucketLimiter(store storage.Storage, capacity int, leakRate float64
 // Allow enforces the leaky bucket rules per key.
 func (lb *LeakyBucketLimiter) Allow(ctx context.Context, key string) (Result, error) {
 	stateKey := lb.stateKey(key)
-	state := leakyBucketState{
-		LastLeak: lb.now(),
-	}
-	loaded, err := loadState(ctx, lb.store, stateKey, &state)
+	state, _, err := lb.current(ctx, stateKey)
 	if err != nil {
 		return Result{}, err
 	}
-	if !loaded {
-		state.LastLeak = lb.now()
-	}
 
-	now := lb.now()
-	elapsed := now.Sub(state.LastLeak).Seconds()
-	if elapsed > 0 {
-		leaked := elapsed * lb.leakRate
-		state.WaterLevel = math.Max(0, state.WaterLevel-leaked)
-		state.LastLeak = now
+	result := lb.decide(&state)
+
+	if err := saveState(ctx, lb.store, stateKey, &state, lb.stateTTL(state), lb.stateCodec); err != nil {
+		return Result{}, err
 	}
 
+	return result, nil
+}
+
+// decide adds one request to state if the bucket has room.
+func (lb *LeakyBucketLimiter) decide(state *leakyBucketState) Result {
 	result := Result{
-		Limit: int(lb.capacity),
+		Limit:  int(lb.capacity),
+		Window: time.Duration(lb.capacity / lb.leakRate * float64(time.Second)),
 	}
 
 	if state.WaterLevel+1 <= lb.capacity {
@@ -71,17 +70,100 @@ func (lb *LeakyBucketLimiter) Allow(ctx context.Context, key string) (Result, er
 	}
 
 	result.Remaining = int(math.Max(0, lb.capacity-state.WaterLevel))
+	return result
+}
+
+// Inspect reports the bucket for key and the Result its next request would get.
+func (lb *LeakyBucketLimiter) Inspect(ctx context.Context, key string) (KeyState, error) {
+	stateKey := lb.stateKey(key)
+	state, loaded, err := lb.current(ctx, stateKey)
+	if err != nil {
+		return KeyState{}, err
+	}
+	next := state
+	return KeyState{
+		StateKey:  stateKey,
+		Exists:    loaded,
+		State:     state,
+		Remaining: int(math.Max(0, lb.capacity-state.WaterLevel)),
+		Result:    lb.decide(&next),
+	}, nil
+}
+
+// Reset empties the bucket for key.
+func (lb *LeakyBucketLimiter) Reset(ctx context.Context, key string) error {
+	return lb.store.Delete(ctx, lb.stateKey(key))
+}
+
+// Grant makes room for n extra requests in the bucket for key, even below empty.
+func (lb *LeakyBucketLimiter) Grant(ctx context.Context, key string, n int) error {
+	stateKey := lb.stateKey(key)
+	state, _, err := lb.current(ctx, stateKey)
+	if err != nil {
+		return err
+	}
+	state.WaterLevel -= float64(n)
+	return saveState(ctx, lb.store, stateKey, &state, lb.stateTTL(state), lb.stateCodec)
+}
 
-	if err := saveState(ctx, lb.store, stateKey, &state, lb.ttl); err != nil {
+// Consume records n requests that were already admitted elsewhere, e.g. by a
+// local cache, and returns the resulting bucket state.
+func (lb *LeakyBucketLimiter) Consume(ctx context.Context, key string, n int) (Result, error) {
+	stateKey := lb.stateKey(key)
+	state, _, err := lb.current(ctx, stateKey)
+	if err != nil {
 		return Result{}, err
 	}
+	state.WaterLevel = math.Min(lb.capacity, state.WaterLevel+float64(n))
 
+	result := Result{
+		Allowed:   state.WaterLevel+1 <= lb.capacity,
+		Limit:     int(lb.capacity),
+		Remaining: int(math.Max(0, lb.capacity-state.WaterLevel)),
+		Window:    time.Duration(lb.capacity / lb.leakRate * float64(time.Second)),
+	}
+	if err := saveState(ctx, lb.store, stateKey, &state, lb.stateTTL(state), lb.stateCodec); err != nil {
+		return Result{}, err
+	}
 	return result, nil
 }
 
-func (lb *LeakyBucketLimiter) stateKey(key string) string {
-	if lb.keyPrefix == "" {
-		return key
+// current loads the bucket for stateKey and leaks it up to now. loaded
+// reports whether the bucket was found in storage.
+func (lb *LeakyBucketLimiter) current(ctx context.Context, stateKey string) (leakyBucketState, bool, error) {
+	state := leakyBucketState{
+		LastLeak: lb.now(),
 	}
-	return lb.keyPrefix + ":" + key
+	loaded, err := loadState(ctx, lb.store, stateKey, &state, lb.stateCodec)
+	if err != nil {
+		return state, false, err
+	}
+	if !loaded {
+		state.LastLeak = lb.now()
+	}
+
+	now := lb.now()
+	elapsed := now.Sub(state.LastLeak).Seconds()
+	if elapsed > 0 {
+		// A negative level is room granted by an operator; leaking keeps it.
+		if state.WaterLevel > 0 {
+			leaked := elapsed * lb.leakRate
+			state.WaterLevel = math.Max(0, state.WaterLevel-leaked)
+		}
+		state.LastLeak = now
+	}
+	return state, loaded, nil
+}
+
+// stateTTL keeps a bucket holding granted room (a negative level) until it is
+// used: leaking never takes it away, so neither may expiry.
+func (lb *LeakyBucketLimiter) stateTTL(state leakyBucketState) time.Duration {
+	if state.WaterLevel < 0 {
+		return 0
+	}
+	return lb.ttl
+}
+
+func (lb *LeakyBucketLimiter) stateKey(key string) string {
+	return stateKey(lb.store, lb.keyPrefix, key)
 }
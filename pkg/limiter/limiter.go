@@ -22,6 +22,15 @@ type Result struct {
 	RetryAfter time.Duration
 	Limit      int
 	ResetAfter time.Duration
+	// Window is the time span the quota applies to, used for RateLimit-Policy.
+	Window time.Duration
+	// Banned marks a rejection by the penalty box rather than the algorithm.
+	Banned bool
+	// Shadow marks the result of a shadow mode policy. Such requests are
+	// always allowed and get no rate limit headers.
+	Shadow bool
+	// ShadowDenied reports that a shadow mode policy would have rejected the request.
+	ShadowDenied bool
 }
 
 // Limiter is implemented by algorithm instances that can rate limit based on a key.
@@ -0,0 +1,235 @@
+package limiter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Consumer is implemented by limiters that can account for requests admitted
+// elsewhere in one round trip.
+type Consumer interface {
+	Limiter
+	Consume(ctx context.Context, key string, n int) (Result, error)
+}
+
+// LocalCacheConfig tunes LocalCacheLimiter.
+type LocalCacheConfig struct {
+	// SyncInterval is how often local admissions are flushed to the backing
+	// limiter and how long a cached decision is trusted.
+	SyncInterval time.Duration
+	// MaxLocal caps the requests admitted per key between syncs. With R
+	// replicas a key can be over-admitted by at most (R-1) x MaxLocal per interval.
+	MaxLocal int
+}
+
+type localEntry struct {
+	mu       sync.Mutex
+	result   Result
+	pending  int
+	syncedAt time.Time
+	lastUsed time.Time
+}
+
+// LocalCacheLimiter keeps an approximate per-key view of a shared limiter in
+// process. Requests are admitted against the last synchronized Remaining and
+// the local deltas are flushed asynchronously, trading bounded over-admission
+// for far fewer storage round trips on hot keys.
+type LocalCacheLimiter struct {
+	next Consumer
+	cfg  LocalCacheConfig
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*localEntry
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewLocalCacheLimiter wraps next and starts the background flusher.
+func NewLocalCacheLimiter(next Consumer, cfg LocalCacheConfig) *LocalCacheLimiter {
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = 250 * time.Millisecond
+	}
+	if cfg.MaxLocal <= 0 {
+		cfg.MaxLocal = 100
+	}
+	l := &LocalCacheLimiter{
+		next:    next,
+		cfg:     cfg,
+		now:     time.Now,
+		entries: make(map[string]*localEntry),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// Allow admits locally while the cached view has quota left, and consults the
+// backing limiter when the view is stale or the local budget is spent.
+func (l *LocalCacheLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	e := l.entry(key)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	now := l.now()
+	e.lastUsed = now
+	if e.syncedAt.IsZero() || now.Sub(e.syncedAt) >= l.cfg.SyncInterval || e.pending >= l.cfg.MaxLocal {
+		if err := l.flushLocked(ctx, key, e); err != nil {
+			return Result{}, err
+		}
+		result, err := l.next.Allow(ctx, key)
+		if err != nil {
+			return Result{}, err
+		}
+		e.result = result
+		e.syncedAt = now
+		return result, nil
+	}
+
+	result := e.result
+	result.RetryAfter, result.ResetAfter = 0, 0
+	if e.result.Remaining-e.pending > 0 {
+		e.pending++
+		result.Allowed = true
+		result.Remaining = e.result.Remaining - e.pending
+		return result, nil
+	}
+
+	result.Allowed = false
+	result.Remaining = 0
+	result.RetryAfter = e.result.RetryAfter
+	if result.RetryAfter <= 0 {
+		result.RetryAfter = l.cfg.SyncInterval - now.Sub(e.syncedAt)
+	}
+	result.ResetAfter = result.RetryAfter
+	return result, nil
+}
+
+// Inspect flushes local admissions for key and inspects the backing limiter.
+func (l *LocalCacheLimiter) Inspect(ctx context.Context, key string) (KeyState, error) {
+	inspector, ok := l.next.(Inspector)
+	if !ok {
+		return KeyState{}, ErrNotInspectable
+	}
+	e := l.entry(key)
+	e.mu.Lock()
+	err := l.flushLocked(ctx, key, e)
+	e.mu.Unlock()
+	if err != nil {
+		return KeyState{}, err
+	}
+	return inspector.Inspect(ctx, key)
+}
+
+// Reset drops local admissions for key and resets the backing limiter.
+func (l *LocalCacheLimiter) Reset(ctx context.Context, key string) error {
+	inspector, ok := l.next.(Inspector)
+	if !ok {
+		return ErrNotInspectable
+	}
+	l.mu.Lock()
+	delete(l.entries, key)
+	l.mu.Unlock()
+	return inspector.Reset(ctx, key)
+}
+
+// Grant adds quota in the backing limiter and forces key to resync.
+func (l *LocalCacheLimiter) Grant(ctx context.Context, key string, n int) error {
+	inspector, ok := l.next.(Inspector)
+	if !ok {
+		return ErrNotInspectable
+	}
+	if err := inspector.Grant(ctx, key, n); err != nil {
+		return err
+	}
+	e := l.entry(key)
+	e.mu.Lock()
+	e.syncedAt = time.Time{}
+	e.mu.Unlock()
+	return nil
+}
+
+// Close stops the flusher after pushing any outstanding local admissions.
+func (l *LocalCacheLimiter) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.stop)
+		<-l.done
+		l.flushAll(true)
+	})
+	return nil
+}
+
+func (l *LocalCacheLimiter) entry(key string) *localEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e, ok := l.entries[key]
+	if !ok {
+		e = &localEntry{}
+		l.entries[key] = e
+	}
+	return e
+}
+
+// flushLocked pushes pending admissions. e.mu must be held.
+func (l *LocalCacheLimiter) flushLocked(ctx context.Context, key string, e *localEntry) error {
+	if e.pending == 0 {
+		return nil
+	}
+	result, err := l.next.Consume(ctx, key, e.pending)
+	if err != nil {
+		return err
+	}
+	e.pending = 0
+	e.result = result
+	e.syncedAt = l.now()
+	return nil
+}
+
+func (l *LocalCacheLimiter) run() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.flushAll(false)
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// flushAll flushes every key with pending admissions and forgets keys that
+// have been idle for several intervals. Failed flushes are retried next tick.
+func (l *LocalCacheLimiter) flushAll(final bool) {
+	l.mu.Lock()
+	keys := make([]string, 0, len(l.entries))
+	entries := make([]*localEntry, 0, len(l.entries))
+	for key, e := range l.entries {
+		keys = append(keys, key)
+		entries = append(entries, e)
+	}
+	l.mu.Unlock()
+
+	idleAfter := 10 * l.cfg.SyncInterval
+	for i, e := range entries {
+		ctx, cancel := context.WithTimeout(context.Background(), l.cfg.SyncInterval)
+		e.mu.Lock()
+		err := l.flushLocked(ctx, keys[i], e)
+		idle := err == nil && e.pending == 0 && l.now().Sub(e.lastUsed) > idleAfter
+		e.mu.Unlock()
+		cancel()
+
+		if idle && !final {
+			l.mu.Lock()
+			if l.entries[keys[i]] == e {
+				delete(l.entries, keys[i])
+			}
+			l.mu.Unlock()
+		}
+	}
+}
@@ -2,9 +2,14 @@ package limiter
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"path"
 	"strings"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
 )
 
 // KeyFunc extracts a logical identity for a request.
@@ -17,16 +22,65 @@ type Policy struct {
 	Methods []string
 	Limiter Limiter
 	KeyFunc KeyFunc
+	// Algorithm names the limiter algorithm; informational only.
+	Algorithm AlgorithmType
+	// Mode is enforce or shadow; shadow policies never reject requests.
+	Mode PolicyMode
+	// FailureMode decides what happens when Limiter returns an error.
+	FailureMode FailureMode
+	// Fallback is consulted instead of Limiter when FailureMode is fallback.
+	Fallback Limiter
+
+	statePrefix string
+	// algorithm is the configured algorithm, used to build overrides.
+	algorithm config.AlgorithmConfig
+	penalty   *penaltyBox
 }
 
 // Manager selects the proper policy per request.
 type Manager struct {
-	policies []*Policy
+	policies     []*Policy
+	observer     ErrorObserver
+	decisions    DecisionObserver
+	errLogger    *errorLogger
+	shadowLogger *errorLogger
+	resetLogger  *errorLogger
+	// store holds policy state; set when built from config.
+	store       storage.Storage
+	overrides   overrideSet
+	hitters     *heavyHitters
+	decisionLog *DecisionLogger
+	keyHasher   *KeyHasher
+	events      EventPublisher
+	thresholds  []float64
+	// encoding is the state encoding of limiters built after construction.
+	encoding StateEncoding
 }
 
 // NewManager builds a Manager from policies (evaluated in-order).
 func NewManager(policies []*Policy) *Manager {
-	return &Manager{policies: policies}
+	return &Manager{
+		policies:     policies,
+		errLogger:    newErrorLogger(errorLogInterval),
+		shadowLogger: newErrorLogger(errorLogInterval),
+		resetLogger:  newErrorLogger(errorLogInterval),
+		keyHasher:    NewKeyHasher(nil),
+	}
+}
+
+// SetErrorObserver registers a hook notified on every limiter failure.
+func (m *Manager) SetErrorObserver(observer ErrorObserver) {
+	m.observer = observer
+}
+
+// DecisionObserver is notified of the latency of every limiter decision.
+type DecisionObserver interface {
+	ObserveDecision(policy, algorithm string, duration time.Duration)
+}
+
+// SetDecisionObserver registers a hook timing every decision.
+func (m *Manager) SetDecisionObserver(observer DecisionObserver) {
+	m.decisions = observer
 }
 
 // Allow evaluates a request against configured policies.
@@ -42,13 +96,156 @@ func (m *Manager) Allow(ctx context.Context, r *http.Request) (Result, string, b
 		if key == "" {
 			continue
 		}
-		result, err := policy.Limiter.Allow(ctx, key)
+		start := time.Now()
+		spanCtx, span := startDecisionSpan(ctx, policy)
+		result, err := m.evaluate(spanCtx, policy, key)
+		latency := time.Since(start)
+		if m.decisions != nil {
+			m.decisions.ObserveDecision(policy.Name, string(policy.Algorithm), latency)
+		}
+		if m.hitters != nil {
+			m.hitters.record(policy.Name, key, err == nil && !result.Allowed)
+		}
+		if m.events != nil && err == nil && policy.Mode != PolicyModeShadow {
+			m.publishDecision(policy, key, result)
+		}
+		if policy.Mode == PolicyModeShadow {
+			result, err = m.shadow(policy, key, result, err), nil
+		}
+		if m.decisionLog != nil {
+			m.decisionLog.log(spanCtx, m.keyHasher, policy.Name, key, result, latency, err)
+		}
+		endDecisionSpan(span, result, err)
 		return result, policy.Name, true, err
 	}
 
 	return Result{Allowed: true}, "", false, nil
 }
 
+// evaluate applies the penalty box, any override and the policy's limiter to key.
+func (m *Manager) evaluate(ctx context.Context, policy *Policy, key string) (Result, error) {
+	if policy.penalty != nil {
+		wait, err := policy.penalty.banned(ctx, key)
+		if err != nil {
+			return m.handleFailure(ctx, policy, key, err)
+		}
+		if wait > 0 {
+			return bannedResult(wait), nil
+		}
+	}
+
+	instance := policy.Limiter
+	if override := m.overrides.lookup(policy.Name, key); override != nil {
+		instance = override
+	}
+	result, err := instance.Allow(ctx, key)
+	if err != nil {
+		return m.handleFailure(ctx, policy, key, err)
+	}
+	// Shadow policies only observe: their would-be rejections must not
+	// strike or ban keys, which would show up in /admin/bans and persist.
+	if !result.Allowed && policy.penalty != nil && policy.Mode != PolicyModeShadow {
+		m.recordViolation(ctx, policy, key, &result)
+	}
+	return result, nil
+}
+
+// recordViolation counts a rejection towards the penalty box. When it starts
+// a ban, the client is told to retry after the ban instead.
+func (m *Manager) recordViolation(ctx context.Context, policy *Policy, key string, result *Result) {
+	ban, err := policy.penalty.violation(ctx, key)
+	if err != nil {
+		m.errLogger.log(policy.Name, policy.FailureMode, err)
+		return
+	}
+	if ban > 0 {
+		result.RetryAfter, result.ResetAfter = ban, ban
+		m.publishBan(policy, key, ban)
+	}
+}
+
+// handleFailure applies the policy's failure mode to a limiter error.
+func (m *Manager) handleFailure(ctx context.Context, policy *Policy, key string, err error) (Result, error) {
+	mode := policy.FailureMode
+	if mode == "" {
+		mode = FailureModeClosed
+	}
+	if m.observer != nil {
+		m.observer.ObserveStorageError(policy.Name, string(mode))
+	}
+	m.errLogger.log(policy.Name, mode, err)
+
+	switch mode {
+	case FailureModeOpen:
+		return Result{Allowed: true}, nil
+	case FailureModeFallback:
+		if policy.Fallback != nil {
+			return policy.Fallback.Allow(ctx, key)
+		}
+	}
+	return Result{}, err
+}
+
+// Policy returns the policy registered under name.
+func (m *Manager) Policy(name string) (*Policy, bool) {
+	for _, policy := range m.policies {
+		if policy.Name == name {
+			return policy, true
+		}
+	}
+	return nil, false
+}
+
+// Policies returns the configured policies in evaluation order.
+func (m *Manager) Policies() []*Policy {
+	return m.policies
+}
+
+// WrapLimiters replaces each policy's limiter with wrap(policy, limiter). It
+// must be called before the manager serves traffic.
+func (m *Manager) WrapLimiters(wrap func(policy string, l Limiter) Limiter) {
+	for _, policy := range m.policies {
+		policy.Limiter = wrap(policy.Name, policy.Limiter)
+	}
+}
+
+// Close releases background resources held by policy limiters, such as
+// local caches that still have admissions to flush.
+func (m *Manager) Close() error {
+	var firstErr error
+	for _, policy := range m.policies {
+		for _, l := range []Limiter{policy.Limiter, policy.Fallback} {
+			closer, ok := l.(io.Closer)
+			if !ok {
+				continue
+			}
+			if err := closer.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// OwnsStateKey reports whether a storage key holds state of a configured
+// policy. Managers built without config own every key.
+func (m *Manager) OwnsStateKey(key string) bool {
+	known := false
+	for _, policy := range m.policies {
+		if policy.statePrefix == "" {
+			continue
+		}
+		known = true
+		if strings.HasPrefix(key, policy.statePrefix+":") ||
+			strings.HasPrefix(key, policy.statePrefix+overrideStateSuffix+":") ||
+			strings.HasPrefix(key, overrideKeyPrefix+policy.Name+":") ||
+			penaltyOwns(policy, key) {
+			return true
+		}
+	}
+	return !known
+}
+
 func (p *Policy) matches(r *http.Request) bool {
 	if len(p.Methods) > 0 {
 		methodMatch := false
@@ -0,0 +1,236 @@
+package limiter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+const (
+	// overrideKeyPrefix namespaces override definitions in storage.
+	overrideKeyPrefix = "override:"
+	// overrideStateSuffix namespaces the algorithm state of overridden keys so
+	// it never mixes with the state kept under the configured parameters.
+	overrideStateSuffix = "~override"
+)
+
+// ErrInvalidOverride is returned when an override cannot be applied.
+var ErrInvalidOverride = errors.New("invalid override")
+
+// Override temporarily replaces a policy's parameters for one identity.
+// Either Scale or Limit and Window must be set.
+type Override struct {
+	Policy string `json:"policy"`
+	Key    string `json:"key"`
+	// Scale multiplies the policy's configured limits, e.g. 10 for 10x.
+	Scale float64 `json:"scale,omitempty"`
+	// Limit requests per Window replace the configured limits, using the
+	// policy's algorithm.
+	Limit     int           `json:"limit,omitempty"`
+	Window    time.Duration `json:"window,omitempty"`
+	Reason    string        `json:"reason,omitempty"`
+	ExpiresAt time.Time     `json:"expires_at"`
+}
+
+func (o Override) storageKey() string {
+	return overrideKeyPrefix + o.Policy + ":" + o.Key
+}
+
+type activeOverride struct {
+	Override
+	limiter Limiter
+}
+
+// overrideSet is the in-process copy of the overrides in storage. Lookups
+// read an immutable map so the request path takes no lock.
+type overrideSet struct {
+	mu      sync.Mutex
+	current atomic.Pointer[map[string]*activeOverride]
+}
+
+func overrideID(policy, key string) string {
+	return policy + "\x00" + key
+}
+
+// lookup returns the limiter of the live override for key, if any.
+func (s *overrideSet) lookup(policy, key string) Limiter {
+	current := s.current.Load()
+	if current == nil {
+		return nil
+	}
+	active, ok := (*current)[overrideID(policy, key)]
+	if !ok || time.Now().After(active.ExpiresAt) {
+		return nil
+	}
+	return active.limiter
+}
+
+// update copies the current map, applies fn and publishes the result.
+func (s *overrideSet) update(fn func(map[string]*activeOverride)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := make(map[string]*activeOverride)
+	if current := s.current.Load(); current != nil {
+		for id, active := range *current {
+			next[id] = active
+		}
+	}
+	fn(next)
+	s.current.Store(&next)
+}
+
+// SetOverride stores o so every replica applies it until o.ExpiresAt. It
+// takes effect on this replica immediately and on others at their next refresh.
+func (m *Manager) SetOverride(ctx context.Context, o Override) error {
+	active, err := m.buildOverride(o)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(o)
+	if err != nil {
+		return err
+	}
+	if err := m.store.Set(ctx, o.storageKey(), data, time.Until(o.ExpiresAt)); err != nil {
+		return err
+	}
+	m.overrides.update(func(set map[string]*activeOverride) {
+		set[overrideID(o.Policy, o.Key)] = active
+	})
+	return nil
+}
+
+// DeleteOverride removes the override for key under policy.
+func (m *Manager) DeleteOverride(ctx context.Context, policy, key string) error {
+	if m.store == nil {
+		return fmt.Errorf("%w: manager has no storage", ErrInvalidOverride)
+	}
+	if err := m.store.Delete(ctx, Override{Policy: policy, Key: key}.storageKey()); err != nil {
+		return err
+	}
+	m.overrides.update(func(set map[string]*activeOverride) {
+		delete(set, overrideID(policy, key))
+	})
+	return nil
+}
+
+// Overrides lists the live overrides in storage.
+func (m *Manager) Overrides(ctx context.Context) ([]Override, error) {
+	if m.store == nil {
+		return nil, nil
+	}
+	var overrides []Override
+	now := time.Now()
+	err := storage.ScanAll(ctx, m.store, overrideKeyPrefix, func(keys []string) error {
+		for _, key := range keys {
+			data, err := m.store.Get(ctx, key)
+			if errors.Is(err, storage.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			var o Override
+			if err := json.Unmarshal(data, &o); err != nil {
+				slog.Warn("skipping unreadable override", "key", key, "error", err)
+				continue
+			}
+			if now.After(o.ExpiresAt) {
+				continue
+			}
+			overrides = append(overrides, o)
+		}
+		return nil
+	})
+	return overrides, err
+}
+
+// RefreshOverrides reloads overrides from storage, picking up changes made
+// through other replicas. Override limiters keep their state in storage, so
+// rebuilding them is cheap.
+func (m *Manager) RefreshOverrides(ctx context.Context) error {
+	overrides, err := m.Overrides(ctx)
+	if err != nil {
+		return err
+	}
+	next := make(map[string]*activeOverride, len(overrides))
+	for _, o := range overrides {
+		active, err := m.buildOverride(o)
+		if err != nil {
+			slog.Warn("skipping override", "policy", o.Policy, "key", o.Key, "error", err)
+			continue
+		}
+		next[overrideID(o.Policy, o.Key)] = active
+	}
+	m.overrides.update(func(set map[string]*activeOverride) {
+		for id := range set {
+			delete(set, id)
+		}
+		for id, active := range next {
+			set[id] = active
+		}
+	})
+	return nil
+}
+
+// WatchOverrides refreshes overrides every interval until ctx is done.
+func (m *Manager) WatchOverrides(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.RefreshOverrides(ctx); err != nil && ctx.Err() == nil {
+			slog.Warn("failed to refresh overrides", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// buildOverride validates o and builds the limiter enforcing it.
+func (m *Manager) buildOverride(o Override) (*activeOverride, error) {
+	if m.store == nil {
+		return nil, fmt.Errorf("%w: manager has no storage", ErrInvalidOverride)
+	}
+	policy, ok := m.Policy(o.Policy)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPolicy, o.Policy)
+	}
+	if policy.statePrefix == "" {
+		return nil, fmt.Errorf("%w: policy %s has no configured algorithm", ErrInvalidOverride, o.Policy)
+	}
+	if o.Key == "" {
+		return nil, fmt.Errorf("%w: key is required", ErrInvalidOverride)
+	}
+	if !o.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrInvalidOverride)
+	}
+
+	cfg := policy.algorithm
+	switch {
+	case o.Limit > 0 && o.Window > 0:
+		cfg.Limit, cfg.Burst, cfg.RefillRate = o.Limit, o.Limit, o.Limit
+		cfg.Interval = config.Duration(o.Window)
+		cfg.Window = config.Duration(o.Window)
+		cfg.LeakRate = float64(o.Limit) / o.Window.Seconds()
+	case o.Scale > 0:
+		cfg = scaleAlgorithm(cfg, o.Scale)
+	default:
+		return nil, fmt.Errorf("%w: set scale, or limit and window", ErrInvalidOverride)
+	}
+	instance, err := limiterFromConfig(cfg, m.store, policy.Name+overrideStateSuffix, m.encoding)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOverride, err)
+	}
+	m.reportResets(instance, policy.Name)
+	return &activeOverride{Override: o, limiter: instance}, nil
+}
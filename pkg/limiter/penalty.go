@@ -0,0 +1,196 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+// penaltyKeyPrefix namespaces penalty box state in storage.
+const penaltyKeyPrefix = "penalty:"
+
+// ErrNoPenalty is returned for ban operations on a policy without a penalty box.
+var ErrNoPenalty = errors.New("penalty box is not enabled")
+
+// PenaltyConfig bans keys that keep exceeding their limit.
+type PenaltyConfig struct {
+	// Threshold is the number of rejections within Window that triggers a ban.
+	Threshold int
+	Window    time.Duration
+	// BanDuration is the length of the first ban; each further ban doubles
+	// it up to MaxBanDuration.
+	BanDuration    time.Duration
+	MaxBanDuration time.Duration
+	// Decay forgets earlier bans after this long without a new one.
+	Decay time.Duration
+}
+
+// Ban describes a key in the penalty box.
+type Ban struct {
+	Policy string
+	Key    string
+	Until  time.Time
+	// Strikes counts the bans that led to this one, including it.
+	Strikes int
+}
+
+type penaltyState struct {
+	Violations  int       `json:"violations"`
+	WindowStart time.Time `json:"window_start"`
+	Strikes     int       `json:"strikes"`
+	BannedUntil time.Time `json:"banned_until"`
+}
+
+// penaltyBox tracks violations and bans of one policy.
+type penaltyBox struct {
+	store  storage.Storage
+	cfg    PenaltyConfig
+	prefix string
+	now    func() time.Time
+	stateCodec
+}
+
+func newPenaltyBox(store storage.Storage, cfg PenaltyConfig, policy string) *penaltyBox {
+	if cfg.Window <= 0 {
+		cfg.Window = time.Minute
+	}
+	if cfg.BanDuration <= 0 {
+		cfg.BanDuration = time.Minute
+	}
+	if cfg.MaxBanDuration < cfg.BanDuration {
+		cfg.MaxBanDuration = cfg.BanDuration * 60
+	}
+	if cfg.Decay <= 0 {
+		cfg.Decay = 24 * time.Hour
+	}
+	return &penaltyBox{
+		store:  store,
+		cfg:    cfg,
+		prefix: penaltyKeyPrefix + policy,
+		now:    time.Now,
+	}
+}
+
+// banned returns how long key remains banned, or zero.
+func (p *penaltyBox) banned(ctx context.Context, key string) (time.Duration, error) {
+	var state penaltyState
+	if _, err := loadState(ctx, p.store, stateKey(p.store, p.prefix, key), &state, p.stateCodec); err != nil {
+		return 0, err
+	}
+	if wait := state.BannedUntil.Sub(p.now()); wait > 0 {
+		return wait, nil
+	}
+	return 0, nil
+}
+
+// violation records a rejection of key and returns the length of the ban it
+// triggered, or zero.
+func (p *penaltyBox) violation(ctx context.Context, key string) (time.Duration, error) {
+	stateKey := stateKey(p.store, p.prefix, key)
+	var state penaltyState
+	if _, err := loadState(ctx, p.store, stateKey, &state, p.stateCodec); err != nil {
+		return 0, err
+	}
+
+	now := p.now()
+	if now.Sub(state.WindowStart) >= p.cfg.Window {
+		state.Violations = 0
+		state.WindowStart = now
+	}
+	state.Violations++
+
+	var ban time.Duration
+	if state.Violations >= p.cfg.Threshold {
+		state.Strikes++
+		ban = p.cfg.BanDuration
+		for i := 1; i < state.Strikes && ban < p.cfg.MaxBanDuration; i++ {
+			ban *= 2
+		}
+		if ban > p.cfg.MaxBanDuration {
+			ban = p.cfg.MaxBanDuration
+		}
+		state.BannedUntil = now.Add(ban)
+		state.Violations = 0
+	}
+
+	ttl := p.cfg.Window
+	if state.Strikes > 0 {
+		ttl = p.cfg.Decay
+		if until := state.BannedUntil.Sub(now) + p.cfg.Decay; until > ttl {
+			ttl = until
+		}
+	}
+	return ban, saveState(ctx, p.store, stateKey, &state, ttl, p.stateCodec)
+}
+
+// clear lifts any ban on key and forgets its history.
+func (p *penaltyBox) clear(ctx context.Context, key string) error {
+	return p.store.Delete(ctx, stateKey(p.store, p.prefix, key))
+}
+
+// Bans lists the keys currently banned under any policy.
+func (m *Manager) Bans(ctx context.Context) ([]Ban, error) {
+	var bans []Ban
+	for _, policy := range m.policies {
+		box := policy.penalty
+		if box == nil {
+			continue
+		}
+		now := box.now()
+		err := storage.ScanAll(ctx, box.store, box.prefix+":", func(keys []string) error {
+			for _, key := range keys {
+				var state penaltyState
+				loaded, err := loadState(ctx, box.store, key, &state, box.stateCodec)
+				if err != nil {
+					return err
+				}
+				if !loaded || !state.BannedUntil.After(now) {
+					continue
+				}
+				bans = append(bans, Ban{
+					Policy:  policy.Name,
+					Key:     m.identity(box.prefix, key),
+					Until:   state.BannedUntil,
+					Strikes: state.Strikes,
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("policy %s: %w", policy.Name, err)
+		}
+	}
+	return bans, nil
+}
+
+// ClearBan lifts the ban on key under the named policy and forgets its
+// earlier violations.
+func (m *Manager) ClearBan(ctx context.Context, policy, key string) error {
+	p, ok := m.Policy(policy)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownPolicy, policy)
+	}
+	if p.penalty == nil {
+		return fmt.Errorf("policy %s: %w", policy, ErrNoPenalty)
+	}
+	return p.penalty.clear(ctx, key)
+}
+
+// bannedResult is returned for requests of banned keys.
+func bannedResult(wait time.Duration) Result {
+	return Result{
+		Allowed:    false,
+		Banned:     true,
+		RetryAfter: wait,
+		ResetAfter: wait,
+	}
+}
+
+// penaltyOwns reports whether key belongs to a penalty box of policy.
+func penaltyOwns(policy *Policy, key string) bool {
+	return policy.penalty != nil && strings.HasPrefix(key, policy.penalty.prefix+":")
+}
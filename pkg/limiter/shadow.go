@@ -0,0 +1,58 @@
+package limiter
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// PolicyMode decides whether a policy's decisions are enforced.
+type PolicyMode string
+
+const (
+	// PolicyModeEnforce rejects requests over the limit.
+	PolicyModeEnforce PolicyMode = "enforce"
+	// PolicyModeShadow evaluates the limit and records would-be rejections,
+	// but always admits the request.
+	PolicyModeShadow PolicyMode = "shadow"
+)
+
+// ParsePolicyMode validates a configured policy mode. Empty values mean enforce.
+func ParsePolicyMode(value string) (PolicyMode, error) {
+	switch PolicyMode(strings.ToLower(strings.TrimSpace(value))) {
+	case "", PolicyModeEnforce:
+		return PolicyModeEnforce, nil
+	case PolicyModeShadow, "dry_run", "dry-run":
+		return PolicyModeShadow, nil
+	default:
+		return "", fmt.Errorf("unsupported mode %s", value)
+	}
+}
+
+// shadow turns the outcome of a shadow mode policy into an admission,
+// remembering whether it would have been rejected.
+func (m *Manager) shadow(policy *Policy, key string, result Result, err error) Result {
+	denied := err != nil || !result.Allowed
+	reason := "over the limit"
+	switch {
+	case err != nil:
+		reason = err.Error()
+	case result.Banned:
+		reason = "banned"
+	}
+	result = Result{
+		Allowed:      true,
+		Shadow:       true,
+		ShadowDenied: denied,
+		Limit:        result.Limit,
+		Remaining:    result.Remaining,
+		Window:       result.Window,
+	}
+	if !denied {
+		return result
+	}
+	if suppressed, ok := m.shadowLogger.allow(); ok {
+		slog.Info("shadow policy would have denied request", "policy", policy.Name, "key_hash", m.keyHasher.Hash(key), "reason", reason, "suppressed", suppressed)
+	}
+	return result
+}
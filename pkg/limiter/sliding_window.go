@@ -22,6 +22,7 @@ type SlidingWindowLimiter struct {
 	keyPrefix  string
 	ttl        time.Duration
 	now        func() time.Time
+	stateCodec
 }
 
 // NewSlidingWindowLimiter instantiates a limiter with the sliding window algorithm.
@@ -39,13 +40,120 @@ func NewSlidingWindowLimiter(store storage.Storage, limit int, windowSize time.D
 // Allow applies the sliding window count per key.
 func (sw *SlidingWindowLimiter) Allow(ctx context.Context, key string) (Result, error) {
 	stateKey := sw.stateKey(key)
+	state, now, _, err := sw.current(ctx, stateKey)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := sw.decide(&state, now)
+
+	if err := saveState(ctx, sw.store, stateKey, &state, sw.ttl, sw.stateCodec); err != nil {
+		return Result{}, err
+	}
+
+	return result, nil
+}
+
+// decide counts one request against state if the window has room.
+func (sw *SlidingWindowLimiter) decide(state *slidingWindowState, now time.Time) Result {
+	timeIntoWindow, estimatedCount := sw.estimate(*state, now)
+
+	result := Result{
+		Limit:      sw.limit,
+		Remaining:  int(math.Max(0, float64(sw.limit-estimatedCount))),
+		Window:     sw.windowSize,
+		ResetAfter: sw.resetAfter(timeIntoWindow),
+	}
+
+	if estimatedCount < sw.limit {
+		state.CurrCount++
+		result.Allowed = true
+		result.Remaining = int(math.Max(0, float64(sw.limit-(estimatedCount+1))))
+	} else {
+		result.Allowed = false
+		result.RetryAfter = result.ResetAfter
+	}
+	return result
+}
+
+// resetAfter is the time until the current window rolls over, given the
+// seconds already spent in it.
+func (sw *SlidingWindowLimiter) resetAfter(timeIntoWindow float64) time.Duration {
+	reset := sw.windowSize - time.Duration(timeIntoWindow*float64(time.Second))
+	if reset <= 0 || reset > sw.windowSize {
+		return sw.windowSize
+	}
+	return reset
+}
+
+// Inspect reports the window for key and the Result its next request would get.
+func (sw *SlidingWindowLimiter) Inspect(ctx context.Context, key string) (KeyState, error) {
+	stateKey := sw.stateKey(key)
+	state, now, loaded, err := sw.current(ctx, stateKey)
+	if err != nil {
+		return KeyState{}, err
+	}
+	_, estimatedCount := sw.estimate(state, now)
+	next := state
+	return KeyState{
+		StateKey:  stateKey,
+		Exists:    loaded,
+		State:     state,
+		Remaining: int(math.Max(0, float64(sw.limit-estimatedCount))),
+		Result:    sw.decide(&next, now),
+	}, nil
+}
+
+// Reset clears both windows for key.
+func (sw *SlidingWindowLimiter) Reset(ctx context.Context, key string) error {
+	return sw.store.Delete(ctx, sw.stateKey(key))
+}
+
+// Grant allows n extra requests for key in the current window.
+func (sw *SlidingWindowLimiter) Grant(ctx context.Context, key string, n int) error {
+	stateKey := sw.stateKey(key)
+	state, _, _, err := sw.current(ctx, stateKey)
+	if err != nil {
+		return err
+	}
+	state.CurrCount -= n
+	return saveState(ctx, sw.store, stateKey, &state, sw.ttl, sw.stateCodec)
+}
+
+// Consume records n requests that were already admitted elsewhere, e.g. by a
+// local cache, and returns the resulting window state.
+func (sw *SlidingWindowLimiter) Consume(ctx context.Context, key string, n int) (Result, error) {
+	stateKey := sw.stateKey(key)
+	state, now, _, err := sw.current(ctx, stateKey)
+	if err != nil {
+		return Result{}, err
+	}
+	state.CurrCount += n
+
+	timeIntoWindow, estimatedCount := sw.estimate(state, now)
+	result := Result{
+		Allowed:    estimatedCount < sw.limit,
+		Limit:      sw.limit,
+		Remaining:  int(math.Max(0, float64(sw.limit-estimatedCount))),
+		Window:     sw.windowSize,
+		ResetAfter: sw.resetAfter(timeIntoWindow),
+	}
+	if err := saveState(ctx, sw.store, stateKey, &state, sw.ttl, sw.stateCodec); err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
+
+// current loads the window state for stateKey and rotates it up to now.
+// loaded reports whether the state was found in storage.
+func (sw *SlidingWindowLimiter) current(ctx context.Context, stateKey string) (slidingWindowState, time.Time, bool, error) {
 	state := slidingWindowState{
 		CurrWindowStart: sw.now(),
 	}
 
-	loaded, err := loadState(ctx, sw.store, stateKey, &state)
+	loaded, err := loadState(ctx, sw.store, stateKey, &state, sw.stateCodec)
 	if err != nil {
-		return Result{}, err
+		return state, time.Time{}, false, err
 	}
 	if !loaded {
 		state.CurrWindowStart = sw.now()
@@ -54,7 +162,8 @@ func (sw *SlidingWindowLimiter) Allow(ctx context.Context, key string) (Result,
 	now := sw.now()
 	if diff := now.Sub(state.CurrWindowStart); diff >= sw.windowSize {
 		windowsPassed := int(diff / sw.windowSize)
-		if windowsPassed == 1 {
+		if windowsPassed == 1 && state.CurrCount > 0 {
+			// A negative count is quota granted for the current window only.
 			state.PrevCount = state.CurrCount
 		} else {
 			state.PrevCount = 0
@@ -66,43 +175,20 @@ func (sw *SlidingWindowLimiter) Allow(ctx context.Context, key string) (Result,
 		}
 	}
 
+	return state, now, loaded, nil
+}
+
+// estimate weighs the previous window by how much of it still overlaps.
+func (sw *SlidingWindowLimiter) estimate(state slidingWindowState, now time.Time) (float64, int) {
 	timeIntoWindow := now.Sub(state.CurrWindowStart).Seconds()
 	windowSeconds := sw.windowSize.Seconds()
 	weight := (windowSeconds - timeIntoWindow) / windowSeconds
 	if weight < 0 {
 		weight = 0
 	}
-
-	estimatedCount := int(math.Round(float64(state.PrevCount)*weight)) + state.CurrCount
-
-	result := Result{
-		Limit:     sw.limit,
-		Remaining: int(math.Max(0, float64(sw.limit-estimatedCount))),
-	}
-
-	if estimatedCount < sw.limit {
-		state.CurrCount++
-		result.Allowed = true
-		result.Remaining = int(math.Max(0, float64(sw.limit-(estimatedCount+1))))
-	} else {
-		result.Allowed = false
-		result.RetryAfter = sw.windowSize - time.Duration(timeIntoWindow)
-		if result.RetryAfter < 0 {
-			result.RetryAfter = sw.windowSize
-		}
-		result.ResetAfter = result.RetryAfter
-	}
-
-	if err := saveState(ctx, sw.store, stateKey, &state, sw.ttl); err != nil {
-		return Result{}, err
-	}
-
-	return result, nil
+	return timeIntoWindow, int(math.Round(float64(state.PrevCount)*weight)) + state.CurrCount
 }
 
 func (sw *SlidingWindowLimiter) stateKey(key string) string {
-	if sw.keyPrefix == "" {
-		return key
-	}
-	return sw.keyPrefix + ":" + key
+	return stateKey(sw.store, sw.keyPrefix, key)
 }
@@ -4,12 +4,34 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
 )
 
-func loadState[T any](ctx context.Context, store storage.Storage, key string, dst *T) (bool, error) {
+// stateCodec holds the state format settings shared by the algorithms.
+type stateCodec struct {
+	encoding StateEncoding
+	// onReset is told about state that could not be decoded and was discarded.
+	onReset func(stateKey string, err error)
+}
+
+// SetStateEncoding selects the format used for writes.
+func (c *stateCodec) SetStateEncoding(encoding StateEncoding) {
+	c.encoding = encoding
+}
+
+func (c *stateCodec) setResetHandler(fn func(stateKey string, err error)) {
+	c.onReset = fn
+}
+
+// resetReporter is implemented by limiters embedding a stateCodec.
+type resetReporter interface {
+	setResetHandler(fn func(stateKey string, err error))
+}
+
+func loadState[T any](ctx context.Context, store storage.Storage, key string, dst *T, codec stateCodec) (bool, error) {
 	data, err := store.Get(ctx, key)
 	if err != nil {
 		if errors.Is(err, storage.ErrNotFound) {
@@ -17,14 +39,33 @@ func loadState[T any](ctx context.Context, store storage.Storage, key string, ds
 		}
 		return false, err
 	}
+	if isBinaryState(data) {
+		state, ok := any(dst).(binaryState)
+		if !ok {
+			return false, fmt.Errorf("%w: %T has no binary form", ErrUnsupportedState, dst)
+		}
+		if err := decodeBinaryState(data, state); err != nil {
+			if errors.Is(err, ErrUnsupportedState) {
+				// Written by a newer build or a different algorithm; start
+				// fresh, but let the owner know the quota was reset.
+				if codec.onReset != nil {
+					codec.onReset(key, err)
+				}
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	// Values written before the binary codec existed are JSON.
 	if err := json.Unmarshal(data, dst); err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-func saveState(ctx context.Context, store storage.Storage, key string, value any, ttl time.Duration) error {
-	bytes, err := json.Marshal(value)
+func saveState(ctx context.Context, store storage.Storage, key string, value any, ttl time.Duration, codec stateCodec) error {
+	bytes, err := encodeState(value, codec.encoding)
 	if err != nil {
 		return err
 	}
@@ -33,3 +74,21 @@ func saveState(ctx context.Context, store storage.Storage, key string, value any
 	}
 	return store.Set(ctx, key, bytes, ttl)
 }
+
+// encodeState writes value in encoding; the zero encoding means binary.
+func encodeState(value any, encoding StateEncoding) ([]byte, error) {
+	if state, ok := value.(binaryState); ok && encoding != StateEncodingJSON {
+		return encodeBinaryState(state), nil
+	}
+	return json.Marshal(value)
+}
+
+// stateKey namespaces key under prefix. The identity part is hash tagged on
+// sharded stores so every key belonging to one identity shares a slot.
+func stateKey(store storage.Storage, prefix, key string) string {
+	key = storage.HashTag(store, key)
+	if prefix == "" {
+		return key
+	}
+	return prefix + ":" + key
+}
@@ -22,6 +22,7 @@ type TokenBucketLimiter struct {
 	ttl            time.Duration
 	keyPrefix      string
 	now            func() time.Time
+	stateCodec
 }
 
 // NewTokenBucketLimiter builds a token bucket limiter that persists state in Storage.
@@ -40,30 +41,25 @@ func NewTokenBucketLimiter(store storage.Storage, capacity, refillRate int, refi
 // Allow calculates the bucket state for the provided key.
 func (tb *TokenBucketLimiter) Allow(ctx context.Context, key string) (Result, error) {
 	stateKey := tb.stateKey(key)
-	state := tokenBucketState{
-		Tokens:     tb.capacity,
-		LastRefill: tb.now(),
+	state, _, err := tb.current(ctx, stateKey)
+	if err != nil {
+		return Result{}, err
 	}
 
-	if ok, err := tb.load(ctx, stateKey, &state); err != nil {
+	result := tb.decide(&state)
+
+	if err := saveState(ctx, tb.store, stateKey, &state, tb.stateTTL(state), tb.stateCodec); err != nil {
 		return Result{}, err
-	} else if !ok {
-		state.Tokens = tb.capacity
-		state.LastRefill = tb.now()
 	}
 
-	now := tb.now()
-	elapsed := now.Sub(state.LastRefill)
-	if elapsed > 0 {
-		refills := float64(elapsed) / float64(tb.refillInterval)
-		if refills > 0 {
-			state.Tokens = math.Min(tb.capacity, state.Tokens+refills*tb.refillRate)
-			state.LastRefill = now
-		}
-	}
+	return result, nil
+}
 
+// decide takes one token from state if available.
+func (tb *TokenBucketLimiter) decide(state *tokenBucketState) Result {
 	result := Result{
-		Limit: int(tb.capacity),
+		Limit:  int(tb.capacity),
+		Window: tb.window(),
 	}
 
 	if state.Tokens >= 1 {
@@ -83,21 +79,116 @@ func (tb *TokenBucketLimiter) Allow(ctx context.Context, key string) (Result, er
 	}
 
 	result.Remaining = int(math.Max(0, state.Tokens))
+	return result
+}
+
+// Inspect reports the bucket for key and the Result its next request would get.
+func (tb *TokenBucketLimiter) Inspect(ctx context.Context, key string) (KeyState, error) {
+	stateKey := tb.stateKey(key)
+	state, loaded, err := tb.current(ctx, stateKey)
+	if err != nil {
+		return KeyState{}, err
+	}
+	next := state
+	return KeyState{
+		StateKey:  stateKey,
+		Exists:    loaded,
+		State:     state,
+		Remaining: int(math.Max(0, state.Tokens)),
+		Result:    tb.decide(&next),
+	}, nil
+}
 
-	if err := saveState(ctx, tb.store, stateKey, &state, tb.ttl); err != nil {
+// Reset refills the bucket for key.
+func (tb *TokenBucketLimiter) Reset(ctx context.Context, key string) error {
+	return tb.store.Delete(ctx, tb.stateKey(key))
+}
+
+// Grant adds n tokens to the bucket for key, even beyond its capacity.
+func (tb *TokenBucketLimiter) Grant(ctx context.Context, key string, n int) error {
+	stateKey := tb.stateKey(key)
+	state, _, err := tb.current(ctx, stateKey)
+	if err != nil {
+		return err
+	}
+	state.Tokens += float64(n)
+	return saveState(ctx, tb.store, stateKey, &state, tb.stateTTL(state), tb.stateCodec)
+}
+
+// Consume records n requests that were already admitted elsewhere, e.g. by a
+// local cache, and returns the resulting bucket state.
+func (tb *TokenBucketLimiter) Consume(ctx context.Context, key string, n int) (Result, error) {
+	stateKey := tb.stateKey(key)
+	state, _, err := tb.current(ctx, stateKey)
+	if err != nil {
 		return Result{}, err
 	}
+	state.Tokens = math.Max(0, state.Tokens-float64(n))
 
+	result := Result{
+		Allowed:   state.Tokens >= 1,
+		Limit:     int(tb.capacity),
+		Remaining: int(state.Tokens),
+		Window:    tb.window(),
+	}
+	if err := saveState(ctx, tb.store, stateKey, &state, tb.stateTTL(state), tb.stateCodec); err != nil {
+		return Result{}, err
+	}
 	return result, nil
 }
 
+// current loads the bucket for stateKey and refills it up to now. loaded
+// reports whether the bucket was found in storage.
+func (tb *TokenBucketLimiter) current(ctx context.Context, stateKey string) (tokenBucketState, bool, error) {
+	state := tokenBucketState{
+		Tokens:     tb.capacity,
+		LastRefill: tb.now(),
+	}
+
+	loaded, err := tb.load(ctx, stateKey, &state)
+	if err != nil {
+		return state, false, err
+	} else if !loaded {
+		state.Tokens = tb.capacity
+		state.LastRefill = tb.now()
+	}
+
+	now := tb.now()
+	elapsed := now.Sub(state.LastRefill)
+	if elapsed > 0 {
+		refills := float64(elapsed) / float64(tb.refillInterval)
+		if refills > 0 {
+			// Tokens above capacity were granted by an operator; refills keep them.
+			if state.Tokens < tb.capacity {
+				state.Tokens = math.Min(tb.capacity, state.Tokens+refills*tb.refillRate)
+			}
+			state.LastRefill = now
+		}
+	}
+	return state, loaded, nil
+}
+
+// stateTTL keeps a bucket holding granted tokens beyond capacity until they
+// are used: refills never take them away, so neither may expiry.
+func (tb *TokenBucketLimiter) stateTTL(state tokenBucketState) time.Duration {
+	if state.Tokens > tb.capacity {
+		return 0
+	}
+	return tb.ttl
+}
+
+// window is the time it takes an empty bucket to refill completely.
+func (tb *TokenBucketLimiter) window() time.Duration {
+	if tb.refillRate <= 0 {
+		return tb.refillInterval
+	}
+	return time.Duration(tb.capacity / tb.refillRate * float64(tb.refillInterval))
+}
+
 func (tb *TokenBucketLimiter) load(ctx context.Context, key string, dst *tokenBucketState) (bool, error) {
-	return loadState(ctx, tb.store, key, dst)
+	return loadState(ctx, tb.store, key, dst, tb.stateCodec)
 }
 
 func (tb *TokenBucketLimiter) stateKey(key string) string {
-	if tb.keyPrefix == "" {
-		return key
-	}
-	return tb.keyPrefix + ":" + key
+	return stateKey(tb.store, tb.keyPrefix, key)
 }
@@ -0,0 +1,50 @@
+package limiter
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+
+// startDecisionSpan opens the span covering one policy decision.
+func startDecisionSpan(ctx context.Context, policy *Policy) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "ratelimit.decide", trace.WithAttributes(
+		attribute.String("ratelimit.policy", policy.Name),
+		attribute.String("ratelimit.algorithm", string(policy.Algorithm)),
+	))
+}
+
+// endDecisionSpan records the outcome of a decision on span and ends it.
+func endDecisionSpan(span trace.Span, result Result, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetAttributes(ResultAttributes(result)...)
+	}
+	span.End()
+}
+
+// ResultAttributes describes a decision as span attributes.
+func ResultAttributes(result Result) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		attribute.Bool("ratelimit.allowed", result.Allowed),
+		attribute.Int("ratelimit.remaining", result.Remaining),
+		attribute.Int("ratelimit.limit", result.Limit),
+	}
+	if result.RetryAfter > 0 {
+		attrs = append(attrs, attribute.Int64("ratelimit.retry_after_ms", result.RetryAfter.Milliseconds()))
+	}
+	if result.Banned {
+		attrs = append(attrs, attribute.Bool("ratelimit.banned", true))
+	}
+	if result.Shadow {
+		attrs = append(attrs, attribute.Bool("ratelimit.shadow_denied", result.ShadowDenied))
+	}
+	return attrs
+}
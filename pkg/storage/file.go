@@ -0,0 +1,391 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Log record layout (big-endian):
+//
+//	crc32(4) | op(1) | expires unix nanos(8) | key len(4) | value len(4) | key | value
+//
+// The checksum covers everything after itself, so a record torn by a crash is
+// detected on replay and the log is truncated back to the last good record.
+const (
+	fileOpSet    byte = 1
+	fileOpDelete byte = 2
+
+	fileRecordHeader = 4 + 1 + 8 + 4 + 4
+	maxFileRecord    = 64 << 20
+
+	defaultCompactMinBytes = 4 << 20
+)
+
+// FileConfig configures FileStorage.
+type FileConfig struct {
+	// Path is the append-only log file. Its directory is created if needed.
+	Path string
+	// SyncInterval batches fsync calls. Zero syncs after every write.
+	SyncInterval time.Duration
+	// CompactInterval is how often the log is checked for compaction.
+	CompactInterval time.Duration
+	// CompactMinBytes is the smallest log size that is worth compacting.
+	CompactMinBytes int64
+	// Memory bounds the in-memory layer serving reads.
+	Memory MemoryConfig
+}
+
+// FileStorage is a single-node persistent Storage. Live entries are served from
+// memory and every mutation is appended to a checksummed log that is replayed on
+// startup and periodically compacted down to the live entries.
+type FileStorage struct {
+	mem *MemoryStorage
+	cfg FileConfig
+
+	mu          sync.Mutex
+	file        *os.File
+	writer      *bufio.Writer
+	size        int64
+	compactedAt int64
+	dirty       bool
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewFileStorage opens (or creates) the log at cfg.Path and replays it.
+func NewFileStorage(cfg FileConfig) (*FileStorage, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("file storage path is required")
+	}
+	if cfg.CompactInterval <= 0 {
+		cfg.CompactInterval = 5 * time.Minute
+	}
+	if cfg.CompactMinBytes <= 0 {
+		cfg.CompactMinBytes = defaultCompactMinBytes
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, err
+	}
+
+	f := &FileStorage{
+		mem:  NewMemoryStorageWithConfig(cfg.Memory),
+		cfg:  cfg,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := f.replay(); err != nil {
+		_ = f.mem.Close()
+		return nil, err
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		_ = f.mem.Close()
+		return nil, err
+	}
+	f.file = file
+	f.writer = bufio.NewWriter(file)
+	f.compactedAt = f.size
+
+	go f.background()
+	return f, nil
+}
+
+// Get returns the value for key if present and not expired.
+func (f *FileStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	return f.mem.Get(ctx, key)
+}
+
+// Set persists value and applies it in memory.
+func (f *FileStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var expires time.Time
+	if ttl > 0 {
+		expires = time.Now().Add(ttl)
+	}
+	return f.append(fileOpSet, key, value, expires, func() error {
+		return f.mem.Set(ctx, key, value, ttl)
+	})
+}
+
+// Delete persists the removal of key.
+func (f *FileStorage) Delete(ctx context.Context, key string) error {
+	return f.append(fileOpDelete, key, nil, time.Time{}, func() error {
+		return f.mem.Delete(ctx, key)
+	})
+}
+
+// Memory returns the in-memory layer, e.g. to export its metrics.
+func (f *FileStorage) Memory() *MemoryStorage {
+	return f.mem
+}
+
+// Scan lists live keys from memory.
+func (f *FileStorage) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	return f.mem.Scan(ctx, prefix, cursor)
+}
+
+// Compact rewrites the log so it only contains live entries.
+func (f *FileStorage) Compact() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.compactLocked()
+}
+
+// Close flushes pending writes, stops background work and closes the log.
+func (f *FileStorage) Close() error {
+	var err error
+	f.closeOnce.Do(func() {
+		close(f.stop)
+		<-f.done
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		err = f.syncLocked()
+		if cerr := f.file.Close(); err == nil {
+			err = cerr
+		}
+		f.file = nil
+		_ = f.mem.Close()
+	})
+	return err
+}
+
+// append logs a record and runs apply to change memory in the same critical
+// section, so compaction and concurrent writers see log and memory agree.
+func (f *FileStorage) append(op byte, key string, value []byte, expires time.Time, apply func() error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file == nil {
+		return fmt.Errorf("file storage closed")
+	}
+	n, err := writeFileRecord(f.writer, op, key, value, expires)
+	if err != nil {
+		return err
+	}
+	f.size += int64(n)
+	f.dirty = true
+	if err := apply(); err != nil {
+		return err
+	}
+	if f.cfg.SyncInterval <= 0 {
+		return f.syncLocked()
+	}
+	return nil
+}
+
+// syncLocked must be called with mu held.
+func (f *FileStorage) syncLocked() error {
+	if !f.dirty || f.file == nil {
+		return nil
+	}
+	if err := f.writer.Flush(); err != nil {
+		return err
+	}
+	if err := f.file.Sync(); err != nil {
+		return err
+	}
+	f.dirty = false
+	return nil
+}
+
+func (f *FileStorage) background() {
+	defer close(f.done)
+
+	var syncC <-chan time.Time
+	if f.cfg.SyncInterval > 0 {
+		ticker := time.NewTicker(f.cfg.SyncInterval)
+		defer ticker.Stop()
+		syncC = ticker.C
+	}
+	compact := time.NewTicker(f.cfg.CompactInterval)
+	defer compact.Stop()
+
+	for {
+		select {
+		case <-syncC:
+			f.mu.Lock()
+			_ = f.syncLocked()
+			f.mu.Unlock()
+		case <-compact.C:
+			f.mu.Lock()
+			// Compact once the log has at least doubled since the last rewrite.
+			if f.size >= f.cfg.CompactMinBytes && f.size >= 2*f.compactedAt {
+				_ = f.compactLocked()
+			}
+			f.mu.Unlock()
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// compactLocked must be called with mu held. The new log is written to a
+// temporary file, synced and renamed over the old one, so a crash at any point
+// leaves either the old or the new log intact.
+func (f *FileStorage) compactLocked() error {
+	if f.file == nil {
+		return fmt.Errorf("file storage closed")
+	}
+	if err := f.syncLocked(); err != nil {
+		return err
+	}
+
+	tmpPath := f.cfg.Path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tmp)
+	var size int64
+	for key, entry := range f.mem.snapshot() {
+		n, err := writeFileRecord(writer, fileOpSet, key, entry.value, entry.expires)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		size += int64(n)
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, f.cfg.Path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(f.cfg.Path))
+
+	file, err := os.OpenFile(f.cfg.Path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	_ = f.file.Close()
+	f.file = file
+	f.writer = bufio.NewWriter(file)
+	f.size = size
+	f.compactedAt = size
+	return nil
+}
+
+// replay loads the log into memory, truncating a torn tail left by a crash.
+func (f *FileStorage) replay() error {
+	file, err := os.OpenFile(f.cfg.Path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ctx := context.Background()
+	now := time.Now()
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		op, key, value, expires, n, err := readFileRecord(reader)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// Everything after the last valid record is discarded.
+			if terr := file.Truncate(offset); terr != nil {
+				return terr
+			}
+			break
+		}
+		offset += int64(n)
+
+		switch op {
+		case fileOpSet:
+			if !expires.IsZero() && !expires.After(now) {
+				_ = f.mem.Delete(ctx, key)
+				continue
+			}
+			var ttl time.Duration
+			if !expires.IsZero() {
+				ttl = expires.Sub(now)
+			}
+			_ = f.mem.Set(ctx, key, value, ttl)
+		case fileOpDelete:
+			_ = f.mem.Delete(ctx, key)
+		}
+	}
+	f.size = offset
+	return nil
+}
+
+func writeFileRecord(w io.Writer, op byte, key string, value []byte, expires time.Time) (int, error) {
+	buf := make([]byte, fileRecordHeader, fileRecordHeader+len(key)+len(value))
+	buf[4] = op
+	var nanos int64
+	if !expires.IsZero() {
+		nanos = expires.UnixNano()
+	}
+	binary.BigEndian.PutUint64(buf[5:], uint64(nanos))
+	binary.BigEndian.PutUint32(buf[13:], uint32(len(key)))
+	binary.BigEndian.PutUint32(buf[17:], uint32(len(value)))
+	buf = append(buf, key...)
+	buf = append(buf, value...)
+	binary.BigEndian.PutUint32(buf[0:], crc32.ChecksumIEEE(buf[4:]))
+	return w.Write(buf)
+}
+
+var errCorruptRecord = errors.New("storage: corrupt log record")
+
+func readFileRecord(r io.Reader) (op byte, key string, value []byte, expires time.Time, n int, err error) {
+	header := make([]byte, fileRecordHeader)
+	if _, err = io.ReadFull(r, header); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			err = errCorruptRecord
+		}
+		return
+	}
+	keyLen := binary.BigEndian.Uint32(header[13:])
+	valueLen := binary.BigEndian.Uint32(header[17:])
+	if keyLen+valueLen > maxFileRecord {
+		err = errCorruptRecord
+		return
+	}
+	body := make([]byte, keyLen+valueLen)
+	if _, err = io.ReadFull(r, body); err != nil {
+		err = errCorruptRecord
+		return
+	}
+	crc := crc32.NewIEEE()
+	_, _ = crc.Write(header[4:])
+	_, _ = crc.Write(body)
+	if crc.Sum32() != binary.BigEndian.Uint32(header[0:]) {
+		err = errCorruptRecord
+		return
+	}
+
+	op = header[4]
+	if nanos := int64(binary.BigEndian.Uint64(header[5:])); nanos != 0 {
+		expires = time.Unix(0, nanos)
+	}
+	key = string(body[:keyLen])
+	value = body[keyLen:]
+	n = fileRecordHeader + len(body)
+	return
+}
+
+// syncDir makes a rename durable on filesystems that need it.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+}
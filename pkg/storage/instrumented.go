@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+
+// OpObserver receives the latency and outcome of every storage operation.
+type OpObserver interface {
+	ObserveStorageOp(driver, op string, duration time.Duration, err error)
+}
+
+// InstrumentedStorage reports every operation of the wrapped store to an
+// OpObserver and traces it as an OpenTelemetry span. ErrNotFound is reported
+// as success.
+type InstrumentedStorage struct {
+	next     Storage
+	driver   string
+	observer OpObserver
+}
+
+// NewInstrumentedStorage wraps next, labelling its operations with driver.
+// observer may be nil to trace only.
+func NewInstrumentedStorage(next Storage, driver string, observer OpObserver) *InstrumentedStorage {
+	return &InstrumentedStorage{next: next, driver: driver, observer: observer}
+}
+
+// Get reads key from the wrapped store.
+func (s *InstrumentedStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	ctx, span, start := s.start(ctx, "get")
+	value, err := s.next.Get(ctx, key)
+	s.observe(span, "get", start, err)
+	return value, err
+}
+
+// Set writes key to the wrapped store.
+func (s *InstrumentedStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, span, start := s.start(ctx, "set")
+	err := s.next.Set(ctx, key, value, ttl)
+	s.observe(span, "set", start, err)
+	return err
+}
+
+// Delete removes key from the wrapped store.
+func (s *InstrumentedStorage) Delete(ctx context.Context, key string) error {
+	ctx, span, start := s.start(ctx, "delete")
+	err := s.next.Delete(ctx, key)
+	s.observe(span, "delete", start, err)
+	return err
+}
+
+// Scan lists keys of the wrapped store.
+func (s *InstrumentedStorage) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	ctx, span, start := s.start(ctx, "scan")
+	keys, next, err := s.next.Scan(ctx, prefix, cursor)
+	s.observe(span, "scan", start, err)
+	return keys, next, err
+}
+
+// UsesHashTags forwards the wrapped store's hash tag requirement.
+func (s *InstrumentedStorage) UsesHashTags() bool {
+	tagger, ok := s.next.(HashTagger)
+	return ok && tagger.UsesHashTags()
+}
+
+// Ping checks the wrapped store's connectivity.
+func (s *InstrumentedStorage) Ping(ctx context.Context) error {
+	return Ping(ctx, s.next)
+}
+
+// Unwrap returns the wrapped store.
+func (s *InstrumentedStorage) Unwrap() Storage {
+	return s.next
+}
+
+func (s *InstrumentedStorage) start(ctx context.Context, op string) (context.Context, trace.Span, time.Time) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "storage."+op, trace.WithAttributes(
+		attribute.String("storage.driver", s.driver),
+		attribute.String("storage.operation", op),
+	))
+	return ctx, span, time.Now()
+}
+
+func (s *InstrumentedStorage) observe(span trace.Span, op string, start time.Time, err error) {
+	duration := time.Since(start)
+	if errors.Is(err, ErrNotFound) {
+		err = nil
+	}
+	span.SetAttributes(attribute.Float64("storage.latency_ms", float64(duration.Microseconds())/1000))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+	if s.observer != nil {
+		s.observer.ObserveStorageOp(s.driver, op, duration, err)
+	}
+}
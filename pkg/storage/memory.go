@@ -1,44 +1,196 @@
 package storage
 
 import (
+	"container/list"
 	"context"
+	"fmt"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// EvictionPolicy selects which entry MemoryStorage drops when it is full.
+type EvictionPolicy string
+
+const (
+	// EvictLRU drops the least recently used entry.
+	EvictLRU EvictionPolicy = "lru"
+	// EvictRandom drops an arbitrary entry.
+	EvictRandom EvictionPolicy = "random"
+)
+
+// ParseEvictionPolicy validates a configured eviction policy. Empty values mean lru.
+func ParseEvictionPolicy(value string) (EvictionPolicy, error) {
+	switch EvictionPolicy(strings.ToLower(strings.TrimSpace(value))) {
+	case "", EvictLRU:
+		return EvictLRU, nil
+	case EvictRandom:
+		return EvictRandom, nil
+	default:
+		return "", fmt.Errorf("unsupported eviction policy %s", value)
+	}
+}
+
+const (
+	randomEvictionSamples = 5
+	// scanBatch is roughly how many keys Scan returns per call.
+	scanBatch = 1000
+)
+
+// MemoryConfig bounds a MemoryStorage.
+type MemoryConfig struct {
+	// SweepInterval runs a background janitor removing expired entries. Zero disables it.
+	SweepInterval time.Duration
+	// MaxEntries caps the number of stored keys. Zero means unbounded. The cap
+	// is split evenly across shards, so eviction order is per shard.
+	MaxEntries int
+	// Eviction decides which entry is dropped once MaxEntries is reached.
+	Eviction EvictionPolicy
+	// Shards is the number of independently locked partitions, rounded up to a
+	// power of two. Zero picks a default based on GOMAXPROCS.
+	Shards int
+}
+
 // memoryEntry represents a memoized value with an optional expiration.
 type memoryEntry struct {
 	value   []byte
 	expires time.Time
+	// elem tracks recency when LRU eviction is enabled.
+	elem *list.Element
 }
 
-// MemoryStorage is an in-memory implementation that satisfies Storage.
-type MemoryStorage struct {
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expires.IsZero() && now.After(e.expires)
+}
+
+// memoryShard is one lock-protected partition of the key space.
+type memoryShard struct {
 	mu    sync.RWMutex
-	store map[string]memoryEntry
+	store map[string]*memoryEntry
+	// lru orders keys from most (front) to least (back) recently used.
+	lru        *list.List
+	maxEntries int
+}
+
+// MemoryStorage is an in-memory implementation that satisfies Storage. Keys are
+// hash-partitioned across shards so unrelated keys never contend on one lock.
+type MemoryStorage struct {
+	shards    []*memoryShard
+	mask      uint32
+	cfg       MemoryConfig
+	evictions atomic.Uint64
+
+	stop      chan struct{}
+	closeOnce sync.Once
 }
 
-// NewMemoryStorage creates a new MemoryStorage instance.
+// NewMemoryStorage creates a new unbounded MemoryStorage instance.
 func NewMemoryStorage() *MemoryStorage {
-	return &MemoryStorage{
-		store: make(map[string]memoryEntry),
+	return NewMemoryStorageWithConfig(MemoryConfig{})
+}
+
+// NewMemoryStorageWithConfig creates a MemoryStorage with optional bounds and a
+// background janitor. Call Close to stop the janitor.
+func NewMemoryStorageWithConfig(cfg MemoryConfig) *MemoryStorage {
+	if cfg.Eviction == "" {
+		cfg.Eviction = EvictLRU
+	}
+	count := shardCount(cfg.Shards, cfg.MaxEntries)
+	m := &MemoryStorage{
+		shards: make([]*memoryShard, count),
+		mask:   uint32(count - 1),
+		cfg:    cfg,
+		stop:   make(chan struct{}),
 	}
+	for i := range m.shards {
+		shard := &memoryShard{store: make(map[string]*memoryEntry)}
+		if cfg.MaxEntries > 0 {
+			// Spread the remainder so the shard caps add up to MaxEntries.
+			shard.maxEntries = cfg.MaxEntries / count
+			if i < cfg.MaxEntries%count {
+				shard.maxEntries++
+			}
+			if cfg.Eviction == EvictLRU {
+				shard.lru = list.New()
+			}
+		}
+		m.shards[i] = shard
+	}
+	if cfg.SweepInterval > 0 {
+		go m.janitor(cfg.SweepInterval)
+	}
+	return m
+}
+
+// shardCount rounds requested up to a power of two, never exceeding maxEntries.
+func shardCount(requested, maxEntries int) int {
+	if requested <= 0 {
+		requested = runtime.GOMAXPROCS(0) * 4
+	}
+	if maxEntries > 0 && requested > maxEntries {
+		requested = maxEntries
+	}
+	count := 1
+	for count < requested {
+		count <<= 1
+	}
+	if maxEntries > 0 && count > maxEntries {
+		count >>= 1
+	}
+	return count
+}
+
+// shard picks the partition for key using FNV-1a.
+func (m *MemoryStorage) shard(key string) *memoryShard {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+	hash := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		hash ^= uint32(key[i])
+		hash *= 16777619
+	}
+	return m.shards[hash&m.mask]
 }
 
 // Get returns the value for a key if present and not expired.
 func (m *MemoryStorage) Get(_ context.Context, key string) ([]byte, error) {
-	m.mu.RLock()
-	entry, ok := m.store[key]
-	m.mu.RUnlock()
+	shard := m.shard(key)
+	if shard.lru != nil {
+		return shard.getLRU(key)
+	}
+
+	shard.mu.RLock()
+	entry, ok := shard.store[key]
+	shard.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if entry.expired(time.Now()) {
+		shard.mu.Lock()
+		if current, ok := shard.store[key]; ok && current == entry {
+			shard.remove(key, entry)
+		}
+		shard.mu.Unlock()
+		return nil, ErrNotFound
+	}
+	return entry.value, nil
+}
+
+func (s *memoryShard) getLRU(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.store[key]
 	if !ok {
 		return nil, ErrNotFound
 	}
-	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
-		m.mu.Lock()
-		delete(m.store, key)
-		m.mu.Unlock()
+	if entry.expired(time.Now()) {
+		s.remove(key, entry)
 		return nil, ErrNotFound
 	}
+	s.lru.MoveToFront(entry.elem)
 	return entry.value, nil
 }
 
@@ -48,20 +200,196 @@ func (m *MemoryStorage) Set(_ context.Context, key string, value []byte, ttl tim
 	if ttl > 0 {
 		expires = time.Now().Add(ttl)
 	}
-
-	m.mu.Lock()
-	m.store[key] = memoryEntry{
+	entry := &memoryEntry{
 		value:   append([]byte(nil), value...),
 		expires: expires,
 	}
-	m.mu.Unlock()
+
+	shard := m.shard(key)
+	shard.mu.Lock()
+	m.setLocked(shard, key, entry)
+	shard.mu.Unlock()
 	return nil
 }
 
+// setLocked stores entry, evicting if the shard is full. shard.mu must be held.
+func (m *MemoryStorage) setLocked(shard *memoryShard, key string, entry *memoryEntry) {
+	if existing, ok := shard.store[key]; ok {
+		entry.elem = existing.elem
+	} else if shard.maxEntries > 0 && len(shard.store) >= shard.maxEntries {
+		if shard.evictOne() {
+			m.evictions.Add(1)
+		}
+	}
+	if shard.lru != nil {
+		if entry.elem == nil {
+			entry.elem = shard.lru.PushFront(key)
+		} else {
+			shard.lru.MoveToFront(entry.elem)
+		}
+	}
+	shard.store[key] = entry
+}
+
 // Delete removes a key from the storage.
 func (m *MemoryStorage) Delete(_ context.Context, key string) error {
-	m.mu.Lock()
-	delete(m.store, key)
-	m.mu.Unlock()
+	shard := m.shard(key)
+	shard.mu.Lock()
+	if entry, ok := shard.store[key]; ok {
+		shard.remove(key, entry)
+	}
+	shard.mu.Unlock()
+	return nil
+}
+
+// Scan returns the matching keys of one or more shards; the cursor is the
+// index of the next shard to visit.
+func (m *MemoryStorage) Scan(_ context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	var keys []string
+	now := time.Now()
+	for i := cursor; i < uint64(len(m.shards)); i++ {
+		shard := m.shards[i]
+		shard.mu.RLock()
+		for key, entry := range shard.store {
+			if strings.HasPrefix(key, prefix) && !entry.expired(now) {
+				keys = append(keys, key)
+			}
+		}
+		shard.mu.RUnlock()
+		if len(keys) >= scanBatch && i+1 < uint64(len(m.shards)) {
+			return keys, i + 1, nil
+		}
+	}
+	return keys, 0, nil
+}
+
+// Len returns the number of stored entries, including expired ones not yet swept.
+func (m *MemoryStorage) Len() int {
+	total := 0
+	for _, shard := range m.shards {
+		shard.mu.RLock()
+		total += len(shard.store)
+		shard.mu.RUnlock()
+	}
+	return total
+}
+
+// Evictions returns how many entries were dropped to respect MaxEntries.
+func (m *MemoryStorage) Evictions() uint64 {
+	return m.evictions.Load()
+}
+
+// Sweep removes every expired entry and returns how many were removed. Shards
+// are locked one at a time so traffic on other shards is never blocked.
+func (m *MemoryStorage) Sweep() int {
+	removed := 0
+	for _, shard := range m.shards {
+		now := time.Now()
+		shard.mu.Lock()
+		for key, entry := range shard.store {
+			if entry.expired(now) {
+				shard.remove(key, entry)
+				removed++
+			}
+		}
+		shard.mu.Unlock()
+	}
+	return removed
+}
+
+// Close stops the background janitor. It is safe to call more than once.
+func (m *MemoryStorage) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.stop)
+	})
 	return nil
 }
+
+func (m *MemoryStorage) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.Sweep()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// evictOne must be called with mu held. It reports whether an entry was dropped.
+func (s *memoryShard) evictOne() bool {
+	var victim string
+	if s.lru != nil {
+		back := s.lru.Back()
+		if back == nil {
+			return false
+		}
+		victim = back.Value.(string)
+	} else {
+		// Map iteration order is randomized; sample a few keys and prefer an
+		// expired one over a live one.
+		now := time.Now()
+		sampled := 0
+		for key, entry := range s.store {
+			if sampled == 0 || entry.expired(now) {
+				victim = key
+			}
+			sampled++
+			if entry.expired(now) || sampled >= randomEvictionSamples {
+				break
+			}
+		}
+	}
+	entry, ok := s.store[victim]
+	if !ok {
+		return false
+	}
+	s.remove(victim, entry)
+	return true
+}
+
+// remove must be called with mu held.
+func (s *memoryShard) remove(key string, entry *memoryEntry) {
+	delete(s.store, key)
+	if s.lru != nil && entry.elem != nil {
+		s.lru.Remove(entry.elem)
+	}
+}
+
+// drain removes and returns every live entry.
+func (m *MemoryStorage) drain() map[string]*memoryEntry {
+	entries := make(map[string]*memoryEntry)
+	now := time.Now()
+	for _, shard := range m.shards {
+		shard.mu.Lock()
+		for key, entry := range shard.store {
+			if !entry.expired(now) {
+				entries[key] = entry
+			}
+		}
+		shard.store = make(map[string]*memoryEntry)
+		if shard.lru != nil {
+			shard.lru.Init()
+		}
+		shard.mu.Unlock()
+	}
+	return entries
+}
+
+// snapshot returns a copy of every live entry without removing them.
+func (m *MemoryStorage) snapshot() map[string]*memoryEntry {
+	entries := make(map[string]*memoryEntry)
+	now := time.Now()
+	for _, shard := range m.shards {
+		shard.mu.RLock()
+		for key, entry := range shard.store {
+			if !entry.expired(now) {
+				entries[key] = &memoryEntry{value: entry.value, expires: entry.expires}
+			}
+		}
+		shard.mu.RUnlock()
+	}
+	return entries
+}
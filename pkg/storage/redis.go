@@ -2,33 +2,123 @@ package storage
 
 import (
 	"context"
+	"crypto/tls"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// Redis deployment modes accepted by RedisConfig.Mode.
+const (
+	RedisModeStandalone = "standalone"
+	RedisModeSentinel   = "sentinel"
+	RedisModeCluster    = "cluster"
+)
+
 // RedisStorage implements Storage using Redis.
 type RedisStorage struct {
-	client *redis.Client
+	client  redis.UniversalClient
+	cluster bool
 }
 
 // RedisConfig describes the connection settings.
 type RedisConfig struct {
-	Addr     string
-	Username string
-	Password string
-	DB       int
+	// Mode is standalone (default), sentinel or cluster.
+	Mode string
+	// Addr is the standalone server address.
+	Addr string
+	// Addrs lists sentinel addresses or cluster seed nodes.
+	Addrs      []string
+	MasterName string
+	Username   string
+	Password   string
+	DB         int
+
+	SentinelUsername string
+	SentinelPassword string
+
+	PoolSize     int
+	MinIdleConns int
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	TLSConfig    *tls.Config
 }
 
 // NewRedisStorage returns a Storage backed by Redis.
 func NewRedisStorage(cfg RedisConfig) *RedisStorage {
-	client := redis.NewClient(&redis.Options{
-		Addr:     cfg.Addr,
-		Username: cfg.Username,
-		Password: cfg.Password,
-		DB:       cfg.DB,
-	})
-	return &RedisStorage{client: client}
+	opts := &redis.UniversalOptions{
+		Addrs:                 cfg.Addrs,
+		Username:              cfg.Username,
+		Password:              cfg.Password,
+		DB:                    cfg.DB,
+		SentinelUsername:      cfg.SentinelUsername,
+		SentinelPassword:      cfg.SentinelPassword,
+		PoolSize:              cfg.PoolSize,
+		MinIdleConns:          cfg.MinIdleConns,
+		DialTimeout:           cfg.DialTimeout,
+		ReadTimeout:           cfg.ReadTimeout,
+		WriteTimeout:          cfg.WriteTimeout,
+		ContextTimeoutEnabled: true,
+		TLSConfig:             cfg.TLSConfig,
+	}
+
+	mode := strings.ToLower(cfg.Mode)
+	switch mode {
+	case RedisModeSentinel:
+		opts.MasterName = cfg.MasterName
+	case RedisModeCluster:
+		opts.IsClusterMode = true
+		opts.DB = 0
+		if len(opts.Addrs) == 0 && cfg.Addr != "" {
+			opts.Addrs = []string{cfg.Addr}
+		}
+	default:
+		if cfg.Addr != "" {
+			opts.Addrs = []string{cfg.Addr}
+		}
+	}
+
+	return &RedisStorage{
+		client:  redis.NewUniversalClient(opts),
+		cluster: mode == RedisModeCluster,
+	}
+}
+
+// Validate reports configuration errors before a client is built.
+func (cfg RedisConfig) Validate() error {
+	switch strings.ToLower(cfg.Mode) {
+	case "", RedisModeStandalone:
+		if cfg.Addr == "" && len(cfg.Addrs) == 0 {
+			return fmt.Errorf("redis address is required")
+		}
+		if len(cfg.Addrs) > 1 || (cfg.Addr != "" && len(cfg.Addrs) > 0) {
+			return fmt.Errorf("redis standalone mode takes a single address; use sentinel or cluster mode for several")
+		}
+	case RedisModeSentinel:
+		if cfg.MasterName == "" {
+			return fmt.Errorf("redis master_name is required in sentinel mode")
+		}
+		if len(cfg.Addrs) == 0 {
+			return fmt.Errorf("redis addresses must list the sentinels")
+		}
+	case RedisModeCluster:
+		if len(cfg.Addrs) == 0 && cfg.Addr == "" {
+			return fmt.Errorf("redis addresses must list cluster seed nodes")
+		}
+	default:
+		return fmt.Errorf("unsupported redis mode %s", cfg.Mode)
+	}
+	return nil
+}
+
+// UsesHashTags reports whether keys should carry hash tags (cluster mode).
+func (r *RedisStorage) UsesHashTags() bool {
+	return r.cluster
 }
 
 // Get fetches a value from Redis.
@@ -50,6 +140,94 @@ func (r *RedisStorage) Delete(ctx context.Context, key string) error {
 	return r.client.Del(ctx, key).Err()
 }
 
+// Scan walks keys with SCAN ... MATCH prefix*, one SCAN round trip per
+// call. In cluster mode the masters are walked one after another, ordered by
+// address; the returned cursor holds the master's index in its top
+// clusterCursorNodeBits bits and that master's own cursor below. As with
+// SCAN itself, keys moved by a resharding during the walk may be missed.
+func (r *RedisStorage) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	match := escapeGlob(prefix) + "*"
+	clusterClient, ok := r.client.(*redis.ClusterClient)
+	if !ok {
+		return r.client.Scan(ctx, cursor, match, scanBatch).Result()
+	}
+
+	masters, err := clusterMasters(ctx, clusterClient)
+	if err != nil {
+		return nil, 0, err
+	}
+	index := int(cursor >> clusterCursorShift)
+	if index >= len(masters) {
+		return nil, 0, nil
+	}
+	keys, next, err := masters[index].Scan(ctx, cursor&clusterCursorMask, match, scanBatch).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	if next > clusterCursorMask {
+		return nil, 0, fmt.Errorf("redis: scan cursor %d of %s does not fit the cluster cursor", next, masters[index].Options().Addr)
+	}
+	if next == 0 {
+		index++
+		if index == len(masters) {
+			return keys, 0, nil
+		}
+	}
+	return keys, uint64(index)<<clusterCursorShift | next, nil
+}
+
+// A cluster scan cursor packs the master's index into its top
+// clusterCursorNodeBits bits.
+const (
+	clusterCursorNodeBits = 16
+	clusterCursorShift    = 64 - clusterCursorNodeBits
+	clusterCursorMask     = 1<<clusterCursorShift - 1
+)
+
+// clusterMasters lists the cluster's masters in a stable order.
+func clusterMasters(ctx context.Context, clusterClient *redis.ClusterClient) ([]*redis.Client, error) {
+	var (
+		mu      sync.Mutex
+		masters []*redis.Client
+	)
+	err := clusterClient.ForEachMaster(ctx, func(_ context.Context, node *redis.Client) error {
+		mu.Lock()
+		masters = append(masters, node)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(masters, func(i, j int) bool {
+		return masters[i].Options().Addr < masters[j].Options().Addr
+	})
+	return masters, nil
+}
+
+// escapeGlob quotes the characters SCAN MATCH treats as patterns.
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		switch c {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Ping checks the connection to Redis; in cluster mode every master must answer.
+func (r *RedisStorage) Ping(ctx context.Context) error {
+	if clusterClient, ok := r.client.(*redis.ClusterClient); ok {
+		return clusterClient.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+			return node.Ping(ctx).Err()
+		})
+	}
+	return r.client.Ping(ctx).Err()
+}
+
 // Close closes the Redis client connection.
 func (r *RedisStorage) Close() error {
 	return r.client.Close()
@@ -0,0 +1,324 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a ResilientStorage circuit breaker.
+type CircuitState int
+
+const (
+	// CircuitClosed routes operations to the primary store.
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen lets a single probe through to the primary store.
+	CircuitHalfOpen
+	// CircuitOpen serves every operation from the local fallback.
+	CircuitOpen
+)
+
+// String returns the lower-case state name.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half_open"
+	case CircuitOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
+
+// ResyncPolicy decides what happens to fallback state once the circuit closes.
+type ResyncPolicy string
+
+const (
+	// ResyncDiscard drops the fallback state; the primary store stays authoritative.
+	ResyncDiscard ResyncPolicy = "discard"
+	// ResyncWriteBack copies fallback entries (with remaining TTLs) to the primary store.
+	ResyncWriteBack ResyncPolicy = "write_back"
+)
+
+// ParseResyncPolicy validates a configured resync policy. Empty values mean discard.
+func ParseResyncPolicy(value string) (ResyncPolicy, error) {
+	switch ResyncPolicy(strings.ToLower(strings.TrimSpace(value))) {
+	case "", ResyncDiscard:
+		return ResyncDiscard, nil
+	case ResyncWriteBack:
+		return ResyncWriteBack, nil
+	default:
+		return "", fmt.Errorf("unsupported resync policy %s", value)
+	}
+}
+
+// ResilientConfig tunes ResilientStorage.
+type ResilientConfig struct {
+	// Timeout bounds every primary operation.
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failures that trips the circuit.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before probing the primary.
+	OpenTimeout time.Duration
+	// Resync is applied to fallback state when the circuit closes again.
+	Resync ResyncPolicy
+	// FallbackMaxEntries caps the keys held while the circuit is open.
+	FallbackMaxEntries int
+	// OnStateChange, when set, is called after every circuit transition,
+	// outside the breaker's lock.
+	OnStateChange func(from, to CircuitState)
+}
+
+// ResilientStorage wraps a primary Storage with deadlines and a circuit breaker,
+// serving from an in-memory fallback while the primary is unavailable.
+type ResilientStorage struct {
+	primary  Storage
+	fallback *MemoryStorage
+	cfg      ResilientConfig
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+
+	// resyncs tracks background write-backs, stopped by Close.
+	resyncs    sync.WaitGroup
+	stopResync context.CancelFunc
+	resyncCtx  context.Context
+}
+
+// NewResilientStorage wraps primary using cfg, filling in defaults.
+func NewResilientStorage(primary Storage, cfg ResilientConfig) *ResilientStorage {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 100 * time.Millisecond
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 10 * time.Second
+	}
+	if cfg.Resync == "" {
+		cfg.Resync = ResyncDiscard
+	}
+	if cfg.FallbackMaxEntries <= 0 {
+		cfg.FallbackMaxEntries = 100_000
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ResilientStorage{
+		primary: primary,
+		fallback: NewMemoryStorageWithConfig(MemoryConfig{
+			SweepInterval: time.Minute,
+			MaxEntries:    cfg.FallbackMaxEntries,
+		}),
+		cfg:        cfg,
+		now:        time.Now,
+		resyncCtx:  ctx,
+		stopResync: cancel,
+	}
+}
+
+// State reports the current circuit state.
+func (r *ResilientStorage) State() CircuitState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.state
+}
+
+// Ping checks the primary store directly, whatever the circuit state.
+func (r *ResilientStorage) Ping(ctx context.Context) error {
+	return Ping(ctx, r.primary)
+}
+
+// Get reads from the primary store, or from the fallback while the circuit is open.
+func (r *ResilientStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	var value []byte
+	err := r.do(ctx, func(ctx context.Context, store Storage) error {
+		var err error
+		value, err = store.Get(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+// Set writes to the primary store, or to the fallback while the circuit is open.
+func (r *ResilientStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.do(ctx, func(ctx context.Context, store Storage) error {
+		return store.Set(ctx, key, value, ttl)
+	})
+}
+
+// Delete removes key from the active store.
+func (r *ResilientStorage) Delete(ctx context.Context, key string) error {
+	return r.do(ctx, func(ctx context.Context, store Storage) error {
+		return store.Delete(ctx, key)
+	})
+}
+
+// Scan lists keys of the store holding live state: the primary while the
+// circuit is closed, the fallback otherwise. Scans serve inspection rather
+// than requests, so they are bounded only by ctx and never count towards the
+// breaker; a slow scan of a large keyspace cannot trip the circuit. A cursor
+// is only meaningful for the store that returned it, so an iteration
+// spanning a circuit transition may be incomplete.
+func (r *ResilientStorage) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	if r.State() != CircuitClosed {
+		return r.fallback.Scan(ctx, prefix, cursor)
+	}
+	return r.primary.Scan(ctx, prefix, cursor)
+}
+
+// UsesHashTags forwards the primary store's hash tag requirement.
+func (r *ResilientStorage) UsesHashTags() bool {
+	tagger, ok := r.primary.(HashTagger)
+	return ok && tagger.UsesHashTags()
+}
+
+// Close stops any write-back in progress and closes the primary store when it
+// supports it.
+func (r *ResilientStorage) Close() error {
+	r.stopResync()
+	r.resyncs.Wait()
+	_ = r.fallback.Close()
+	if closer, ok := r.primary.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (r *ResilientStorage) do(ctx context.Context, op func(context.Context, Storage) error) error {
+	if !r.acquire() {
+		return op(ctx, r.fallback)
+	}
+
+	opCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	err := op(opCtx, r.primary)
+	cancel()
+
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		if ctx.Err() != nil {
+			// The caller gave up; that says nothing about the primary.
+			r.release()
+			return err
+		}
+		r.recordFailure()
+		return err
+	}
+	r.recordSuccess()
+	return err
+}
+
+// acquire reports whether the operation may use the primary store.
+func (r *ResilientStorage) acquire() bool {
+	r.mu.Lock()
+	var notify func()
+	defer func() {
+		r.mu.Unlock()
+		notify()
+	}()
+	notify = func() {}
+	switch r.state {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if r.now().Sub(r.openedAt) < r.cfg.OpenTimeout {
+			return false
+		}
+		notify = r.transition(CircuitHalfOpen)
+		fallthrough
+	default:
+		if r.probing {
+			return false
+		}
+		r.probing = true
+		return true
+	}
+}
+
+func (r *ResilientStorage) release() {
+	r.mu.Lock()
+	r.probing = false
+	r.mu.Unlock()
+}
+
+func (r *ResilientStorage) recordFailure() {
+	r.mu.Lock()
+	r.probing = false
+	r.failures++
+	notify := func() {}
+	if r.state == CircuitHalfOpen || r.failures >= r.cfg.FailureThreshold {
+		r.openedAt = r.now()
+		notify = r.transition(CircuitOpen)
+	}
+	r.mu.Unlock()
+	notify()
+}
+
+func (r *ResilientStorage) recordSuccess() {
+	r.mu.Lock()
+	r.probing = false
+	r.failures = 0
+	recovered := r.state != CircuitClosed
+	notify := func() {}
+	if recovered {
+		notify = r.transition(CircuitClosed)
+	}
+	r.mu.Unlock()
+	notify()
+
+	if recovered {
+		r.resync()
+	}
+}
+
+// transition must be called with mu held. It returns the state change
+// notification, which the caller runs once mu is released.
+func (r *ResilientStorage) transition(to CircuitState) func() {
+	from := r.state
+	r.state = to
+	if from == to || r.cfg.OnStateChange == nil {
+		return func() {}
+	}
+	return func() { r.cfg.OnStateChange(from, to) }
+}
+
+// resync applies the configured policy to the fallback state and empties it.
+// Write-back runs in the background so the request that closed the circuit
+// is not held up by the replay.
+func (r *ResilientStorage) resync() {
+	entries := r.fallback.drain()
+	if r.cfg.Resync != ResyncWriteBack || len(entries) == 0 {
+		return
+	}
+	r.resyncs.Add(1)
+	go func() {
+		defer r.resyncs.Done()
+		r.writeBack(r.resyncCtx, entries)
+	}()
+}
+
+func (r *ResilientStorage) writeBack(ctx context.Context, entries map[string]*memoryEntry) {
+	now := r.now()
+	for key, entry := range entries {
+		ttl := time.Duration(0)
+		if !entry.expires.IsZero() {
+			ttl = entry.expires.Sub(now)
+			if ttl <= 0 {
+				continue
+			}
+		}
+		opCtx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+		err := r.primary.Set(opCtx, key, entry.value, ttl)
+		cancel()
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotVersion is the snapshot format written by Export.
+//
+// A snapshot is JSON lines: a header object followed by one object per entry.
+// Readers ignore unknown fields, so later versions may add fields freely;
+// bumping the version is reserved for incompatible changes.
+const SnapshotVersion = 1
+
+type snapshotHeader struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Entries   int       `json:"entries"`
+}
+
+type snapshotEntry struct {
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// ErrSnapshotVersion is returned when a snapshot was written by a newer format.
+var ErrSnapshotVersion = errors.New("storage: unsupported snapshot version")
+
+// Export writes every live entry, with its absolute expiry, to w.
+func (m *MemoryStorage) Export(w io.Writer) (int, error) {
+	entries := m.snapshot()
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	if err := enc.Encode(snapshotHeader{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+		Entries:   len(entries),
+	}); err != nil {
+		return 0, err
+	}
+	for key, entry := range entries {
+		if err := enc.Encode(snapshotEntry{Key: key, Value: entry.value, ExpiresAt: entry.expires}); err != nil {
+			return 0, err
+		}
+	}
+	return len(entries), buf.Flush()
+}
+
+// Import loads a snapshot produced by Export. Entries that have expired since
+// the snapshot was taken are skipped, as are keys rejected by keep (when set),
+// e.g. state belonging to policies that no longer exist. The whole snapshot is
+// decoded before anything is stored, so a truncated or corrupt snapshot
+// changes nothing.
+func (m *MemoryStorage) Import(r io.Reader, keep func(key string) bool) (int, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, fmt.Errorf("snapshot header: %w", err)
+	}
+	if header.Version < 1 || header.Version > SnapshotVersion {
+		return 0, fmt.Errorf("%w: %d", ErrSnapshotVersion, header.Version)
+	}
+
+	var entries []snapshotEntry
+	for {
+		var entry snapshotEntry
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, fmt.Errorf("snapshot entry %d: %w", len(entries)+1, err)
+		}
+		entries = append(entries, entry)
+	}
+
+	now := time.Now()
+	imported := 0
+	for _, entry := range entries {
+		if entry.Key == "" || (keep != nil && !keep(entry.Key)) {
+			continue
+		}
+		if !entry.ExpiresAt.IsZero() && !entry.ExpiresAt.After(now) {
+			continue
+		}
+		shard := m.shard(entry.Key)
+		shard.mu.Lock()
+		m.setLocked(shard, entry.Key, &memoryEntry{value: entry.Value, expires: entry.ExpiresAt})
+		shard.mu.Unlock()
+		imported++
+	}
+	return imported, nil
+}
+
+// SaveSnapshotFile atomically writes a snapshot of m to path.
+func SaveSnapshotFile(m *MemoryStorage, path string) (int, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return 0, err
+	}
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	n, err := m.Export(file)
+	if err == nil {
+		err = file.Sync()
+	}
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(tmpPath)
+		return 0, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, err
+	}
+	syncDir(filepath.Dir(path))
+	return n, nil
+}
+
+// LoadSnapshotFile imports the snapshot at path. A missing file is not an error.
+func LoadSnapshotFile(m *MemoryStorage, path string, keep func(key string) bool) (int, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return m.Import(file, keep)
+}
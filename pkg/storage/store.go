@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -17,4 +18,78 @@ type Storage interface {
 	Get(ctx context.Context, key string) ([]byte, error)
 	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
 	Delete(ctx context.Context, key string) error
+	// Scan returns a batch of live keys starting with prefix and the cursor
+	// for the next batch. Iteration starts at cursor 0 and is complete when
+	// the returned cursor is 0. As with Redis SCAN, keys present for the
+	// whole iteration are returned at least once; others may be missed.
+	Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error)
+}
+
+// ScanAll calls fn for every key under prefix, batch by batch.
+func ScanAll(ctx context.Context, store Storage, prefix string, fn func(keys []string) error) error {
+	var cursor uint64
+	for {
+		keys, next, err := store.Scan(ctx, prefix, cursor)
+		if err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if err := fn(keys); err != nil {
+				return err
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// Pinger is implemented by stores backed by a remote server, to check that
+// the server is reachable.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks store's connectivity. Stores that are not Pingers are local
+// and always reachable.
+func Ping(ctx context.Context, store Storage) error {
+	if pinger, ok := store.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// HashTagger is implemented by stores that shard keys across nodes, such as
+// Redis Cluster. Keys sharing a hash tag are guaranteed to live on one slot.
+type HashTagger interface {
+	UsesHashTags() bool
+}
+
+// HashTag wraps key in a hash tag ("{key}") when store shards by hash tag.
+// Braces in key are escaped, since keys are often client identities and a
+// "}" would otherwise move the tag and pick the slot.
+func HashTag(store Storage, key string) string {
+	if usesHashTags(store) {
+		return "{" + tagEscaper.Replace(key) + "}"
+	}
+	return key
+}
+
+// HashUntag reverses HashTag.
+func HashUntag(store Storage, key string) string {
+	if usesHashTags(store) && strings.HasPrefix(key, "{") && strings.HasSuffix(key, "}") {
+		return tagUnescaper.Replace(key[1 : len(key)-1])
+	}
+	return key
+}
+
+var (
+	tagEscaper   = strings.NewReplacer("%", "%25", "{", "%7B", "}", "%7D")
+	tagUnescaper = strings.NewReplacer("%25", "%", "%7B", "{", "%7D", "}")
+)
+
+func usesHashTags(store Storage) bool {
+	tagger, ok := store.(HashTagger)
+	return ok && tagger.UsesHashTags()
 }
@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/admin"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func adminRequest(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminKeysInspectResetAndGrant(t *testing.T) {
+	ctx := context.Background()
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("strict")}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	handler := admin.NewHandler("secret")
+	handler.HandleKeys(manager)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.RemoteAddr = "10.1.1.1:5000"
+	if res, _, _, _ := manager.Allow(ctx, req); !res.Allowed {
+		t.Fatal("first request should be allowed")
+	}
+
+	rec := adminRequest(handler, http.MethodGet, "/admin/policies/strict/keys/10.1.1.1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("inspect: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var view struct {
+		StateKey string `json:"state_key"`
+		Exists   bool   `json:"exists"`
+		Next     struct {
+			Allowed bool `json:"allowed"`
+		} `json:"next"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &view); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !view.Exists || view.Next.Allowed || view.StateKey != "sw:strict:10.1.1.1" {
+		t.Fatalf("unexpected inspection %+v", view)
+	}
+	if res, _, _, _ := manager.Allow(ctx, req); res.Allowed {
+		t.Fatal("inspecting must not consume or restore quota")
+	}
+
+	rec = adminRequest(handler, http.MethodPost, "/admin/policies/strict/keys/10.1.1.1/grant", `{"requests":2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("grant: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	for i := 0; i < 2; i++ {
+		if res, _, _, _ := manager.Allow(ctx, req); !res.Allowed {
+			t.Fatalf("granted request %d should be allowed", i+1)
+		}
+	}
+	if res, _, _, _ := manager.Allow(ctx, req); res.Allowed {
+		t.Fatal("grant should be used up")
+	}
+
+	rec = adminRequest(handler, http.MethodDelete, "/admin/policies/strict/keys/10.1.1.1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if res, _, _, _ := manager.Allow(ctx, req); !res.Allowed {
+		t.Fatal("reset key should be allowed again")
+	}
+
+	if rec := adminRequest(handler, http.MethodGet, "/admin/policies/missing/keys/x", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown policy, got %d", rec.Code)
+	}
+	if rec := adminRequest(handler, http.MethodGet, "/admin/policies", ""); !strings.Contains(rec.Body.String(), `"name":"strict"`) {
+		t.Fatalf("expected policy listing, got %s", rec.Body)
+	}
+}
+
+func TestTokenBucketGrantSurvivesRefill(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	tb := limiter.NewTokenBucketLimiter(store, 1, 1, 10*time.Millisecond, "tb:grant")
+
+	if err := tb.Grant(ctx, "client", 3); err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	// Idle well past the state's usual TTL of two refill intervals.
+	time.Sleep(60 * time.Millisecond)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if res, _ := tb.Allow(ctx, "client"); res.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 4 {
+		t.Fatalf("expected capacity plus 3 granted tokens, got %d", allowed)
+	}
+}
+
+func TestAdminBulkResetPolicy(t *testing.T) {
+	ctx := context.Background()
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("strict")}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	handler := admin.NewHandler("secret")
+	handler.HandleKeys(manager)
+
+	clients := []string{"10.2.0.1", "10.2.0.2", "10.2.0.3"}
+	for _, ip := range clients {
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.RemoteAddr = ip + ":5000"
+		_, _, _, _ = manager.Allow(ctx, req)
+	}
+	if n, err := manager.ActiveKeys(ctx, "strict"); err != nil || n != len(clients) {
+		t.Fatalf("expected %d active keys, got %d (%v)", len(clients), n, err)
+	}
+
+	rec := adminRequest(handler, http.MethodGet, "/admin/policies/strict/keys", "")
+	var listing struct {
+		Keys   []string `json:"keys"`
+		Cursor uint64   `json:"cursor"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &listing); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(listing.Keys) != len(clients) || listing.Cursor != 0 {
+		t.Fatalf("expected every client listed in one batch, got %+v", listing)
+	}
+
+	rec = adminRequest(handler, http.MethodDelete, "/admin/policies/strict/keys", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"reset":3`) {
+		t.Fatalf("bulk reset: got %d: %s", rec.Code, rec.Body)
+	}
+	if n, _ := manager.ActiveKeys(ctx, "strict"); n != 0 {
+		t.Fatalf("expected no active keys after reset, got %d", n)
+	}
+}
+
+func TestInspectKeyReportsCurrentRemaining(t *testing.T) {
+	algorithms := map[string]config.AlgorithmConfig{
+		"token_bucket":   {Type: "token_bucket", Limit: 5, RefillRate: 5, Interval: config.Duration(time.Hour)},
+		"leaky_bucket":   {Type: "leaky_bucket", Limit: 5, LeakRate: 0.0001},
+		"sliding_window": {Type: "sliding_window", Limit: 5, Window: config.Duration(time.Hour)},
+	}
+	for name, algorithm := range algorithms {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			policy := singleRequestPolicy("api")
+			policy.Algorithm = algorithm
+			manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+			if err != nil {
+				t.Fatalf("failed to build manager: %v", err)
+			}
+			state, err := manager.InspectKey(ctx, "api", "192.0.2.1")
+			if err != nil || state.Remaining != 5 {
+				t.Fatalf("expected full quota for a new key, got %d (err=%v)", state.Remaining, err)
+			}
+			for i := 0; i < 2; i++ {
+				_, _, _, _ = manager.Allow(ctx, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+			}
+			for i := 0; i < 2; i++ {
+				state, err = manager.InspectKey(ctx, "api", "192.0.2.1")
+				if err != nil || state.Remaining != 3 {
+					t.Fatalf("inspection %d: expected 3 remaining, got %d (err=%v)", i+1, state.Remaining, err)
+				}
+			}
+			for i := 0; i < 3; i++ {
+				_, _, _, _ = manager.Allow(ctx, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+			}
+			if state, _ = manager.InspectKey(ctx, "api", "192.0.2.1"); state.Remaining != 0 || state.Result.Allowed {
+				t.Fatalf("expected an exhausted key, got remaining %d", state.Remaining)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package tests
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -14,8 +16,85 @@ func BenchmarkTokenBucket(b *testing.B) {
 	tb := limiter.NewTokenBucketLimiter(store, 100, 100, time.Millisecond*10, "bench")
 	ctx := context.Background()
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		_, _ = tb.Allow(ctx, "bench-client")
 	}
 }
+
+func BenchmarkTokenBucketStateEncoding(b *testing.B) {
+	for _, encoding := range []limiter.StateEncoding{limiter.StateEncodingJSON, limiter.StateEncodingBinary} {
+		b.Run(string(encoding), func(b *testing.B) {
+			store := storage.NewMemoryStorage()
+			tb := limiter.NewTokenBucketLimiter(store, 100, 100, time.Millisecond*10, "bench")
+			tb.SetStateEncoding(encoding)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, _ = tb.Allow(ctx, "bench-client")
+			}
+		})
+	}
+}
+
+// benchmarkKeys spreads parallel benchmarks over many distinct keys.
+var benchmarkKeys = func() []string {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("client-%d", i)
+	}
+	return keys
+}()
+
+func BenchmarkMemoryStorageParallel(b *testing.B) {
+	for _, shards := range []int{1, 0} {
+		name := "shards=default"
+		if shards == 1 {
+			name = "shards=1"
+		}
+		b.Run(name, func(b *testing.B) {
+			store := storage.NewMemoryStorageWithConfig(storage.MemoryConfig{Shards: shards})
+			ctx := context.Background()
+			value := []byte("state")
+			var worker atomic.Uint32
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(worker.Add(1)) * 7919
+				for pb.Next() {
+					key := benchmarkKeys[i%len(benchmarkKeys)]
+					_ = store.Set(ctx, key, value, time.Minute)
+					_, _ = store.Get(ctx, key)
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkTokenBucketParallelManyKeys(b *testing.B) {
+	for _, shards := range []int{1, 0} {
+		name := "shards=default"
+		if shards == 1 {
+			name = "shards=1"
+		}
+		b.Run(name, func(b *testing.B) {
+			store := storage.NewMemoryStorageWithConfig(storage.MemoryConfig{Shards: shards})
+			tb := limiter.NewTokenBucketLimiter(store, 100, 100, 10*time.Millisecond, "bench")
+			ctx := context.Background()
+			var worker atomic.Uint32
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := int(worker.Add(1)) * 7919
+				for pb.Next() {
+					_, _ = tb.Allow(ctx, benchmarkKeys[i%len(benchmarkKeys)])
+					i++
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,303 @@
+package tests
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/server"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/cluster"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+type clusterMember struct {
+	addr    string
+	manager *limiter.Manager
+	node    *cluster.Node
+}
+
+// startCluster runs size in-process nodes, each with its own memory store,
+// authenticating each other with certificates issued by ca.
+func startCluster(t *testing.T, ca *testCA, size, limit int) []*clusterMember {
+	t.Helper()
+	listeners := make([]net.Listener, size)
+	peers := make([]string, size)
+	for i := range listeners {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		listeners[i] = lis
+		peers[i] = lis.Addr().String()
+	}
+
+	policy := singleRequestPolicy("api")
+	policy.Algorithm.Limit = limit
+
+	members := make([]*clusterMember, size)
+	for i := range members {
+		manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+		if err != nil {
+			t.Fatalf("failed to build manager: %v", err)
+		}
+		serverTLS, clientTLS := peerTLS(t, ca, fmt.Sprintf("node-%d", i))
+		node, err := cluster.NewNode(cluster.Config{
+			Self:           peers[i],
+			Peers:          peers,
+			RequestTimeout: time.Second,
+			TLS:            clientTLS,
+		})
+		if err != nil {
+			t.Fatalf("failed to build node: %v", err)
+		}
+		manager.WrapLimiters(node.Wrap)
+
+		s := grpc.NewServer(grpc.Creds(credentials.NewTLS(serverTLS)))
+		node.Register(s)
+		go s.Serve(listeners[i])
+
+		members[i] = &clusterMember{addr: peers[i], manager: manager, node: node}
+		t.Cleanup(func() {
+			s.Stop()
+			node.Close()
+			manager.Close()
+		})
+	}
+	return members
+}
+
+// peerTLS loads a node certificate issued by ca, which is also the peer CA,
+// through a CertReloader. Every member listens on 127.0.0.1.
+func peerTLS(t *testing.T, ca *testCA, name string) (serverTLS, clientTLS *tls.Config) {
+	t.Helper()
+	cfg := serverFiles(t, t.TempDir(), ca, name)
+	cfg.PeerCAFile, cfg.ClientCAFile = cfg.ClientCAFile, ""
+	certs, err := server.NewCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	serverTLS, clientTLS, err = certs.PeerConfigs([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("failed to build peer tls: %v", err)
+	}
+	return serverTLS, clientTLS
+}
+
+func clusterRequest(remoteAddr string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func TestClusterEnforcesGlobalLimitAcrossNodes(t *testing.T) {
+	members := startCluster(t, newTestCA(t), 3, 6)
+
+	allowed := 0
+	for i := 0; i < 12; i++ {
+		member := members[i%len(members)]
+		res, _, _, err := member.manager.Allow(context.Background(), clusterRequest("10.0.0.1:1234"))
+		if err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 6 {
+		t.Fatalf("expected 6 requests allowed across the cluster, got %d", allowed)
+	}
+}
+
+func TestClusterSpreadsKeyOwnership(t *testing.T) {
+	ring := cluster.NewRing([]string{"a:1", "b:1", "c:1"}, 0)
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		counts[ring.Owner(fmt.Sprintf("key-%d", i))]++
+	}
+	for _, member := range []string{"a:1", "b:1", "c:1"} {
+		if counts[member] < 600 {
+			t.Fatalf("expected keys to be spread across members, got %v", counts)
+		}
+	}
+}
+
+func TestClusterFallsBackWhenOwnerIsDown(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	down := lis.Addr().String()
+	lis.Close()
+
+	self := "127.0.0.1:1"
+	node, err := cluster.NewNode(cluster.Config{
+		Self:           self,
+		Peers:          []string{self, down},
+		RequestTimeout: 200 * time.Millisecond,
+		Insecure:       true,
+	})
+	if err != nil {
+		t.Fatalf("failed to build node: %v", err)
+	}
+	defer node.Close()
+
+	local := limiter.NewSlidingWindowLimiter(storage.NewMemoryStorage(), 1, time.Minute, "sw:")
+	routed := node.Wrap("api", local)
+
+	key := ""
+	for i := 0; key == ""; i++ {
+		if candidate := fmt.Sprintf("client-%d", i); node.Owner(candidate) == down {
+			key = candidate
+		}
+	}
+
+	res, err := routed.Allow(context.Background(), key)
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected local fallback to allow the first request, err=%v", err)
+	}
+	res, err = routed.Allow(context.Background(), key)
+	if err != nil || res.Allowed {
+		t.Fatalf("expected local fallback to enforce the limit, err=%v", err)
+	}
+}
+
+func TestClusterAdminActsOnKeyOwner(t *testing.T) {
+	ctx := context.Background()
+	members := startCluster(t, newTestCA(t), 3, 1)
+
+	const key = "10.0.0.7"
+	var other *clusterMember
+	for _, m := range members {
+		if m.node.Owner(key) != m.addr {
+			other = m
+			break
+		}
+	}
+	if res, _, _, err := other.manager.Allow(ctx, clusterRequest(key+":1")); err != nil || !res.Allowed {
+		t.Fatalf("expected the first request to be allowed, err=%v", err)
+	}
+
+	// A node that does not own the key sees and changes the owner's state.
+	state, err := other.manager.InspectKey(ctx, "api", key)
+	if err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	if !state.Exists || state.Remaining != 0 {
+		t.Fatalf("expected the owner's exhausted state, got %+v", state)
+	}
+	if err := other.manager.GrantQuota(ctx, "api", key, 2); err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if got := clusterAllowed(t, members, key, 4); got != 2 {
+		t.Fatalf("expected the grant to reach the owner, got %d allowed", got)
+	}
+	if err := other.manager.ResetKey(ctx, "api", key); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if got := clusterAllowed(t, members, key, 3); got != 1 {
+		t.Fatalf("expected the reset to reach the owner, got %d allowed", got)
+	}
+}
+
+func TestClusterAdminReportsUnreachableOwner(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	down := lis.Addr().String()
+	lis.Close()
+
+	self := "127.0.0.1:1"
+	node, err := cluster.NewNode(cluster.Config{
+		Self:           self,
+		Peers:          []string{self, down},
+		RequestTimeout: 200 * time.Millisecond,
+		Insecure:       true,
+	})
+	if err != nil {
+		t.Fatalf("failed to build node: %v", err)
+	}
+	defer node.Close()
+	routed := node.Wrap("api", limiter.NewSlidingWindowLimiter(storage.NewMemoryStorage(), 1, time.Minute, "sw:")).(limiter.Inspector)
+
+	key := ""
+	for i := 0; key == ""; i++ {
+		if candidate := fmt.Sprintf("client-%d", i); node.Owner(candidate) == down {
+			key = candidate
+		}
+	}
+	if err := routed.Reset(context.Background(), key); err == nil || !strings.Contains(err.Error(), down) {
+		t.Fatalf("expected reset to fail naming the owner %s, got %v", down, err)
+	}
+}
+
+// clusterAllowed sends n requests for key round-robin across members.
+func clusterAllowed(t *testing.T, members []*clusterMember, key string, n int) int {
+	t.Helper()
+	allowed := 0
+	for i := 0; i < n; i++ {
+		res, _, _, err := members[i%len(members)].manager.Allow(context.Background(), clusterRequest(key+":1"))
+		if err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestClusterRequiresPeerTLS(t *testing.T) {
+	peers := []string{"127.0.0.1:1", "127.0.0.1:2"}
+	if _, err := cluster.NewNode(cluster.Config{Self: peers[0], Peers: peers}); err == nil {
+		t.Fatal("expected a multi-node cluster without peer tls to be refused")
+	}
+	node, err := cluster.NewNode(cluster.Config{Self: peers[0], Peers: peers[:1]})
+	if err != nil {
+		t.Fatalf("expected a single node to start without peer tls: %v", err)
+	}
+	node.Close()
+}
+
+func TestClusterRejectsUnauthenticatedPeers(t *testing.T) {
+	ca := newTestCA(t)
+	addr := startCluster(t, ca, 2, 1)[0].addr
+
+	call := func(creds credentials.TransportCredentials) error {
+		conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		var reply map[string]any
+		return conn.Invoke(ctx, "/ratelimiter.cluster.v1.Peer/AllowBatch",
+			map[string]any{"items": []any{}}, &reply, grpc.CallContentSubtype("ratelimiter-cluster-json"))
+	}
+
+	if err := call(insecure.NewCredentials()); err == nil {
+		t.Fatal("expected a plaintext peer to be rejected")
+	}
+	if err := call(credentials.NewTLS(&tls.Config{RootCAs: ca.pool()})); err == nil {
+		t.Fatal("expected a peer without a client certificate to be rejected")
+	}
+	other := newTestCA(t)
+	if err := call(credentials.NewTLS(&tls.Config{RootCAs: ca.pool(), Certificates: []tls.Certificate{other.clientCert(t, "intruder")}})); err == nil {
+		t.Fatal("expected a certificate from another CA to be rejected")
+	}
+	if err := call(credentials.NewTLS(&tls.Config{RootCAs: ca.pool(), Certificates: []tls.Certificate{ca.clientCert(t, "node-9")}})); err != nil {
+		t.Fatalf("expected a peer with a certificate from the cluster CA to be served: %v", err)
+	}
+}
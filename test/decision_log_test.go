@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/server"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func decisionLogEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestDecisionLogSamplesAllowedAndDeniedSeparately(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := server.NewLogger(config.LoggingConfig{Level: "info", Format: "json"}, &buf)
+	if err != nil {
+		t.Fatalf("failed to build logger: %v", err)
+	}
+	policy := singleRequestPolicy("api")
+	policy.Identity = config.IdentityConfig{Type: "header", Key: "X-API-Key"}
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	manager.SetDecisionLogger(limiter.NewDecisionLogger(logger, -1, 1))
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.Header.Set("X-API-Key", "secret-client")
+		_, _, _, _ = manager.Allow(context.Background(), req)
+	}
+
+	entries := decisionLogEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("expected only the 2 denied decisions to be logged, got %d:\n%s", len(entries), buf.String())
+	}
+	entry := entries[0]
+	if entry["msg"] != "rate limit decision" || entry["policy"] != "api" || entry["allowed"] != false {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+	if entry["remaining"] != float64(0) || entry["limit"] != float64(1) {
+		t.Fatalf("expected remaining and limit, got %v", entry)
+	}
+	if _, ok := entry["latency_ms"]; !ok {
+		t.Fatalf("expected latency_ms, got %v", entry)
+	}
+	if hash, _ := entry["key_hash"].(string); len(hash) != 16 {
+		t.Fatalf("expected a 16 character key hash, got %q", hash)
+	}
+	if strings.Contains(buf.String(), "secret-client") {
+		t.Fatal("the raw key must not be logged")
+	}
+}
+
+func TestDecisionLogRespectsLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := server.NewLogger(config.LoggingConfig{Level: "warn", Format: "text"}, &buf)
+	if err != nil {
+		t.Fatalf("failed to build logger: %v", err)
+	}
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("api")}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	manager.SetDecisionLogger(limiter.NewDecisionLogger(logger, 1, 1))
+	_, _, _, _ = manager.Allow(context.Background(), httptest.NewRequest(http.MethodGet, "/api/items", nil))
+
+	if buf.Len() != 0 {
+		t.Fatalf("decisions are logged at info and should be filtered at warn:\n%s", buf.String())
+	}
+}
+
+func TestNewLoggerRejectsUnknownSettings(t *testing.T) {
+	if _, err := server.NewLogger(config.LoggingConfig{Level: "loud"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+	if _, err := server.NewLogger(config.LoggingConfig{Level: "info", Format: "xml"}, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestDecisionLogKeyHashIsKeyed(t *testing.T) {
+	logged := func(hasher *limiter.KeyHasher) string {
+		var buf bytes.Buffer
+		logger, err := server.NewLogger(config.LoggingConfig{Level: "info", Format: "json"}, &buf)
+		if err != nil {
+			t.Fatalf("failed to build logger: %v", err)
+		}
+		manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("api")}, storage.NewMemoryStorage())
+		if err != nil {
+			t.Fatalf("failed to build manager: %v", err)
+		}
+		manager.SetKeyHasher(hasher)
+		manager.SetDecisionLogger(limiter.NewDecisionLogger(logger, 1, 1))
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.RemoteAddr = "203.0.113.7:1234"
+		_, _, _, _ = manager.Allow(context.Background(), req)
+		hash, _ := decisionLogEntries(t, &buf)[0]["key_hash"].(string)
+		return hash
+	}
+
+	mac := hmac.New(sha256.New, []byte("shared"))
+	mac.Write([]byte("203.0.113.7"))
+	want := hex.EncodeToString(mac.Sum(nil)[:8])
+	if got := logged(limiter.NewKeyHasher([]byte("shared"))); got != want {
+		t.Fatalf("expected the HMAC-SHA256 prefix %s, got %s", want, got)
+	}
+	plain := sha256.Sum256([]byte("203.0.113.7"))
+	if got := logged(nil); got == hex.EncodeToString(plain[:8]) || got == want {
+		t.Fatalf("expected a random per-process secret by default, got %s", got)
+	}
+}
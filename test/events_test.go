@@ -0,0 +1,279 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/events"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func allowN(t *testing.T, manager *limiter.Manager, path, remoteAddr string, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if _, _, _, err := manager.Allow(context.Background(), req); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestEventsWebhookReceivesBatchesWithRetries(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []events.Event
+		attempts atomic.Int32
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Events []events.Event `json:"events"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		received = append(received, body.Events...)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	policy := singleRequestPolicy("api")
+	policy.Algorithm.Limit = 5
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	bus := events.NewBus()
+	bus.Attach(events.NewWebhook(events.WebhookConfig{
+		URL:           hook.URL,
+		Headers:       map[string]string{"X-Token": "secret"},
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		MaxRetries:    2,
+		RetryBackoff:  time.Millisecond,
+	}), 16)
+	manager.SetEventPublisher(bus, []float64{0.8})
+
+	allowN(t, manager, "/api/items", "10.5.0.1:1", 7)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.Close(ctx); err != nil {
+		t.Fatalf("bus did not drain: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts.Load() != 2 {
+		t.Fatalf("expected the batch to be retried once, got %d attempts", attempts.Load())
+	}
+	types := make([]events.Type, 0, len(received))
+	for _, e := range received {
+		types = append(types, e.Type)
+	}
+	want := []events.Type{events.TypeThresholdCrossed, events.TypeLimitExceeded, events.TypeLimitExceeded}
+	if len(types) != len(want) {
+		t.Fatalf("expected events %v in one batch, got %v", want, types)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("expected events %v, got %v", want, types)
+		}
+	}
+	threshold := received[0]
+	if threshold.Policy != "api" || threshold.Key != "10.5.0.1" || threshold.Threshold != 0.8 || threshold.Remaining != 1 {
+		t.Fatalf("unexpected threshold event: %+v", threshold)
+	}
+	if received[1].RetryAfterMs <= 0 {
+		t.Fatalf("expected retry_after_ms on limit_exceeded, got %+v", received[1])
+	}
+}
+
+// blockedSink never reads until released, simulating a stuck consumer.
+type blockedSink struct{ release chan struct{} }
+
+func (s blockedSink) Run(_ context.Context, events <-chan events.Event) {
+	<-s.release
+	for range events {
+	}
+}
+
+func TestEventsPublishNeverBlocks(t *testing.T) {
+	sink := blockedSink{release: make(chan struct{})}
+	bus := events.NewBus()
+	bus.Attach(sink, 2)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			bus.Publish(events.Event{Type: events.TypeLimitExceeded, Policy: "api"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a stuck sink")
+	}
+	// limit_exceeded may only fill half of the queue.
+	if got := bus.Dropped(); got != 99 {
+		t.Fatalf("expected 99 dropped events, got %d", got)
+	}
+
+	close(sink.release)
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+}
+
+// recordingSink collects events once released.
+type recordingSink struct {
+	release chan struct{}
+	got     chan []events.Event
+}
+
+func (s recordingSink) Run(_ context.Context, ch <-chan events.Event) {
+	<-s.release
+	var got []events.Event
+	for event := range ch {
+		got = append(got, event)
+	}
+	s.got <- got
+}
+
+func TestEventsFloodDoesNotCrowdOutBans(t *testing.T) {
+	sink := recordingSink{release: make(chan struct{}), got: make(chan []events.Event, 1)}
+	bus := events.NewBus()
+	bus.Attach(sink, 8)
+
+	for i := 0; i < 1000; i++ {
+		bus.Publish(events.Event{Type: events.TypeLimitExceeded, Policy: "api"})
+	}
+	bus.Publish(events.Event{Type: events.TypeBanned, Policy: "api", Key: "10.5.0.9"})
+	bus.Publish(events.Event{Type: events.TypeThresholdCrossed, Policy: "api", Key: "10.5.0.8"})
+
+	close(sink.release)
+	if err := bus.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	counts := make(map[events.Type]int)
+	for _, event := range <-sink.got {
+		counts[event.Type]++
+	}
+	if counts[events.TypeBanned] != 1 || counts[events.TypeThresholdCrossed] != 1 || counts[events.TypeLimitExceeded] != 4 {
+		t.Fatalf("expected the ban and threshold events next to 4 limit_exceeded events, got %v", counts)
+	}
+}
+
+func TestEventsCloseInterruptsWebhookRetries(t *testing.T) {
+	var attempts atomic.Int32
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer hook.Close()
+
+	bus := events.NewBus()
+	bus.Attach(events.NewWebhook(events.WebhookConfig{
+		URL:          hook.URL,
+		MaxRetries:   5,
+		RetryBackoff: time.Hour,
+	}), 16)
+	bus.Publish(events.Event{Type: events.TypeBanned, Policy: "api", Key: "10.5.0.9"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := bus.Close(ctx); err == nil {
+		t.Fatal("expected Close to report the undelivered events")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected Close to interrupt the retry backoff, took %v", elapsed)
+	}
+	if attempts.Load() != 1 {
+		t.Fatalf("expected a single attempt before shutdown, got %d", attempts.Load())
+	}
+}
+
+func TestEventsStreamTailsBans(t *testing.T) {
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{penaltyPolicy(time.Minute)}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	bus := events.NewBus()
+	stream := events.NewStream(16)
+	bus.Attach(stream, 16)
+	manager.SetEventPublisher(bus, nil)
+	srv := httptest.NewServer(stream)
+	defer srv.Close()
+	defer stream.Close()
+
+	resp, err := http.Get(srv.URL + "?type=banned")
+	if err != nil {
+		t.Fatalf("failed to open stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", ct)
+	}
+
+	// One admitted request, then three rejections start the ban.
+	allowN(t, manager, "/api/items", "10.6.0.1:1", 4)
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var eventLine, dataLine string
+	timeout := time.After(2 * time.Second)
+	for dataLine == "" {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				t.Fatal("stream ended early")
+			}
+			if strings.HasPrefix(line, "event: ") {
+				eventLine = line
+			}
+			if strings.HasPrefix(line, "data: ") {
+				dataLine = line
+			}
+		case <-timeout:
+			t.Fatal("no event received")
+		}
+	}
+	if eventLine != "event: banned" {
+		t.Fatalf("expected only banned events, got %q", eventLine)
+	}
+	var event events.Event
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(dataLine, "data: ")), &event); err != nil {
+		t.Fatalf("invalid event data %q: %v", dataLine, err)
+	}
+	if event.Key != "10.6.0.1" || event.RetryAfterMs != time.Minute.Milliseconds() {
+		t.Fatalf("unexpected ban event: %+v", event)
+	}
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+)
+
+var errStorageDown = errors.New("storage down")
+
+// failingStorage fails every operation.
+type failingStorage struct{}
+
+func (failingStorage) Get(context.Context, string) ([]byte, error) { return nil, errStorageDown }
+func (failingStorage) Set(context.Context, string, []byte, time.Duration) error {
+	return errStorageDown
+}
+func (failingStorage) Delete(context.Context, string) error { return errStorageDown }
+func (failingStorage) Scan(context.Context, string, uint64) ([]string, uint64, error) {
+	return nil, 0, errStorageDown
+}
+
+type errorCounter struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *errorCounter) ObserveStorageError(policy string, mode string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[string]int{}
+	}
+	c.counts[policy+"/"+mode]++
+}
+
+func failureManager(t *testing.T, mode string, scale float64) (*limiter.Manager, *errorCounter) {
+	t.Helper()
+	cfg := []config.Policy{{
+		Name:          "guarded",
+		FailureMode:   mode,
+		FallbackScale: scale,
+		Algorithm: config.AlgorithmConfig{
+			Type:   string(limiter.AlgorithmSlidingWindow),
+			Limit:  4,
+			Window: config.Duration(time.Minute),
+		},
+	}}
+	manager, err := limiter.NewManagerFromConfig(cfg, failingStorage{})
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	counter := &errorCounter{}
+	manager.SetErrorObserver(counter)
+	return manager, counter
+}
+
+func TestFailureModeClosedReturnsError(t *testing.T) {
+	manager, counter := failureManager(t, "", 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, _, matched, err := manager.Allow(context.Background(), req)
+	if !matched || !errors.Is(err, errStorageDown) {
+		t.Fatalf("expected storage error, got matched=%v err=%v", matched, err)
+	}
+	if counter.counts["guarded/closed"] != 1 {
+		t.Fatalf("expected one observed error, got %v", counter.counts)
+	}
+}
+
+func TestFailureModeClosedAnswersServiceUnavailable(t *testing.T) {
+	manager, _ := failureManager(t, "closed", 0)
+	handler := middleware.RateLimiter(manager, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 503 with Retry-After, got %d %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestFailureModeOpenAllows(t *testing.T) {
+	manager, counter := failureManager(t, "open", 0)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for i := 0; i < 10; i++ {
+		res, _, _, err := manager.Allow(context.Background(), req)
+		if err != nil || !res.Allowed {
+			t.Fatalf("request %d should fail open, got allowed=%v err=%v", i+1, res.Allowed, err)
+		}
+	}
+	if counter.counts["guarded/open"] != 10 {
+		t.Fatalf("expected ten observed errors, got %v", counter.counts)
+	}
+}
+
+func TestFailureModeFallbackUsesScaledLocalLimiter(t *testing.T) {
+	manager, _ := failureManager(t, "fallback", 0.5)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		res, _, _, err := manager.Allow(context.Background(), req)
+		if err != nil {
+			t.Fatalf("fallback should not surface errors: %v", err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Fatalf("expected 2 requests admitted by the half-scale fallback, got %d", allowed)
+	}
+}
+
+func TestInvalidFailureModeRejected(t *testing.T) {
+	cfg := []config.Policy{{
+		Name:        "bad",
+		FailureMode: "sometimes",
+		Algorithm:   config.AlgorithmConfig{Type: string(limiter.AlgorithmSlidingWindow), Limit: 1, Window: config.Duration(time.Second)},
+	}}
+	if _, err := limiter.NewManagerFromConfig(cfg, failingStorage{}); err == nil {
+		t.Fatal("expected invalid failure mode to be rejected")
+	}
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func openFileStorage(t *testing.T, path string) *storage.FileStorage {
+	t.Helper()
+	store, err := storage.NewFileStorage(storage.FileConfig{Path: path})
+	if err != nil {
+		t.Fatalf("open file storage: %v", err)
+	}
+	return store
+}
+
+func TestFileStoragePersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "limiter.log")
+	ctx := context.Background()
+
+	store := openFileStorage(t, path)
+	_ = store.Set(ctx, "keep", []byte("v1"), time.Hour)
+	_ = store.Set(ctx, "keep", []byte("v2"), time.Hour)
+	_ = store.Set(ctx, "gone", []byte("x"), time.Hour)
+	_ = store.Delete(ctx, "gone")
+	_ = store.Set(ctx, "short", []byte("x"), 10*time.Millisecond)
+	if err := store.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	store = openFileStorage(t, path)
+	defer store.Close()
+	if got, err := store.Get(ctx, "keep"); err != nil || string(got) != "v2" {
+		t.Fatalf("expected v2 after restart, got %q err=%v", got, err)
+	}
+	for _, key := range []string{"gone", "short"} {
+		if _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("%s should not survive restart, got %v", key, err)
+		}
+	}
+}
+
+func TestFileStorageRecoversFromTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limiter.log")
+	ctx := context.Background()
+
+	store := openFileStorage(t, path)
+	_ = store.Set(ctx, "a", []byte("1"), time.Hour)
+	_ = store.Close()
+
+	// Simulate a crash halfway through appending a record.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatalf("open log: %v", err)
+	}
+	_, _ = f.Write([]byte{0xde, 0xad, 0xbe, 0xef, 1, 0, 0})
+	_ = f.Close()
+
+	store = openFileStorage(t, path)
+	if got, err := store.Get(ctx, "a"); err != nil || string(got) != "1" {
+		t.Fatalf("expected intact record, got %q err=%v", got, err)
+	}
+	_ = store.Set(ctx, "b", []byte("2"), time.Hour)
+	_ = store.Close()
+
+	store = openFileStorage(t, path)
+	defer store.Close()
+	if got, err := store.Get(ctx, "b"); err != nil || string(got) != "2" {
+		t.Fatalf("writes after recovery should persist, got %q err=%v", got, err)
+	}
+}
+
+func TestFileStorageCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limiter.log")
+	ctx := context.Background()
+
+	store := openFileStorage(t, path)
+	for i := 0; i < 500; i++ {
+		_ = store.Set(ctx, "hot", []byte("value"), time.Hour)
+	}
+	before, _ := os.Stat(path)
+	if err := store.Compact(); err != nil {
+		t.Fatalf("compact failed: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Fatalf("expected compaction to shrink the log (%d -> %d)", before.Size(), after.Size())
+	}
+	_ = store.Set(ctx, "cold", []byte("c"), time.Hour)
+	_ = store.Close()
+
+	store = openFileStorage(t, path)
+	defer store.Close()
+	for key, want := range map[string]string{"hot": "value", "cold": "c"} {
+		if got, err := store.Get(ctx, key); err != nil || string(got) != want {
+			t.Fatalf("%s: expected %q after compaction, got %q err=%v", key, want, got, err)
+		}
+	}
+}
+
+func TestFileStorageWritesSurviveConcurrentCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limiter.log")
+	ctx := context.Background()
+	store := openFileStorage(t, path)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				_ = store.Set(ctx, fmt.Sprintf("k%d-%d", w, i), []byte("v"), time.Hour)
+			}
+		}(w)
+	}
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = store.Compact()
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	_ = store.Close()
+
+	store = openFileStorage(t, path)
+	defer store.Close()
+	for w := 0; w < 4; w++ {
+		for i := 0; i < 200; i++ {
+			if _, err := store.Get(ctx, fmt.Sprintf("k%d-%d", w, i)); err != nil {
+				t.Fatalf("acknowledged write k%d-%d was lost: %v", w, i, err)
+			}
+		}
+	}
+}
+
+func TestFileStorageBoundsMemory(t *testing.T) {
+	ctx := context.Background()
+	store, err := storage.NewFileStorage(storage.FileConfig{
+		Path:   filepath.Join(t.TempDir(), "limiter.log"),
+		Memory: storage.MemoryConfig{MaxEntries: 10, Shards: 1},
+	})
+	if err != nil {
+		t.Fatalf("open file storage: %v", err)
+	}
+	defer store.Close()
+
+	for i := 0; i < 50; i++ {
+		_ = store.Set(ctx, fmt.Sprintf("k%d", i), []byte("v"), time.Hour)
+	}
+	if n := store.Memory().Len(); n > 10 {
+		t.Fatalf("expected at most 10 entries in memory, got %d", n)
+	}
+}
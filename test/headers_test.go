@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func newHeaderTestHandler(t *testing.T, policyHeaders string, opts ...middleware.Option) http.Handler {
+	t.Helper()
+	cfg := []config.Policy{{
+		Name:   "burst",
+		Routes: []string{"/api/*"},
+		Algorithm: config.AlgorithmConfig{
+			Type:   string(limiter.AlgorithmSlidingWindow),
+			Limit:  2,
+			Window: config.Duration(time.Minute),
+		},
+		Headers: policyHeaders,
+	}}
+	manager, err := limiter.NewManagerFromConfig(cfg, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	headerOpts, err := middleware.HeaderOptions("", cfg)
+	if err != nil {
+		t.Fatalf("failed to build header options: %v", err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return middleware.RateLimiter(manager, nil, append(headerOpts, opts...)...)(ok)
+}
+
+func TestLegacyHeadersByDefault(t *testing.T) {
+	handler := newHeaderTestHandler(t, "")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Fatalf("expected X-RateLimit-Limit 2, got %q", got)
+	}
+	if got := rec.Header().Get("RateLimit"); got != "" {
+		t.Fatalf("expected no RateLimit header, got %q", got)
+	}
+}
+
+func TestIETFHeadersFromServerOption(t *testing.T) {
+	handler := newHeaderTestHandler(t, "", middleware.WithHeaderStyle(middleware.HeaderStyleIETF))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+
+	if got := rec.Header().Get("RateLimit-Policy"); got != `"burst";q=2;w=60;qu="requests"` {
+		t.Fatalf("unexpected RateLimit-Policy %q", got)
+	}
+	if got := rec.Header().Get("RateLimit"); got != `"burst";r=1;t=60` {
+		t.Fatalf("unexpected RateLimit %q", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "" {
+		t.Fatalf("expected no legacy headers, got %q", got)
+	}
+}
+
+func TestPolicyHeaderStyleOverridesServer(t *testing.T) {
+	handler := newHeaderTestHandler(t, "both", middleware.WithHeaderStyle(middleware.HeaderStyleLegacy))
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+		if i < 2 {
+			continue
+		}
+		if rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("expected 429, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("X-RateLimit-Remaining"); got != "0" {
+			t.Fatalf("expected legacy remaining 0, got %q", got)
+		}
+		if got := rec.Header().Get("RateLimit"); got == "" {
+			t.Fatal("expected RateLimit header on denial")
+		}
+	}
+}
+
+func TestInvalidHeaderStyleRejected(t *testing.T) {
+	cfg := []config.Policy{{Name: "bad", Headers: "fancy"}}
+	if _, err := middleware.HeaderOptions("", cfg); err == nil {
+		t.Fatal("expected invalid policy header style to be rejected")
+	}
+	if _, err := middleware.HeaderOptions("fancy", nil); err == nil {
+		t.Fatal("expected invalid server header style to be rejected")
+	}
+	if style, err := middleware.ParseHeaderStyle("draft", ""); err != nil || style != middleware.HeaderStyleIETF {
+		t.Fatalf("expected draft to alias ietf, got %q %v", style, err)
+	}
+}
+
+func TestSlidingWindowResetOnAllowedResponse(t *testing.T) {
+	handler := newHeaderTestHandler(t, "both")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-RateLimit-Reset"); got == "" {
+		t.Fatal("expected X-RateLimit-Reset on an allowed response")
+	}
+}
@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/admin"
+	"github.com/rohankarn35/rate_limiter_golang/internal/server"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func TestHeavyHittersFindTopKeysInBoundedMemory(t *testing.T) {
+	policy := singleRequestPolicy("api")
+	policy.Algorithm.Limit = 5
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	manager.EnableHeavyHitters(20, time.Hour)
+
+	send := func(ip string) {
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.RemoteAddr = ip + ":1"
+		_, _, _, _ = manager.Allow(context.Background(), req)
+	}
+	// A long tail of one-off clients interleaved with two heavy ones.
+	for i := 0; i < 500; i++ {
+		send(fmt.Sprintf("10.5.%d.%d", i/250, i%250))
+		if i%5 == 0 {
+			send("10.9.9.1")
+		}
+		if i%10 == 0 {
+			send("10.9.9.2")
+		}
+	}
+
+	hitters := manager.HeavyHitters(2)
+	if len(hitters) != 1 || len(hitters[0].Active) != 2 {
+		t.Fatalf("expected the top two keys of one policy, got %+v", hitters)
+	}
+	if hitters[0].Active[0].Key != "10.9.9.1" || hitters[0].Active[1].Key != "10.9.9.2" {
+		t.Fatalf("expected the heavy clients first, got %+v", hitters[0].Active)
+	}
+	limited := hitters[0].Limited
+	if len(limited) == 0 || limited[0].Key != "10.9.9.1" || limited[0].Count-limited[0].Error > 95 {
+		t.Fatalf("expected 10.9.9.1 to be the most limited key with 95 rejections, got %+v", limited)
+	}
+
+	handler := admin.NewHandler("secret")
+	handler.HandleHeavyHitters(manager)
+	rec := adminRequest(handler, http.MethodGet, "/admin/heavy-hitters?n=1", "")
+	var body struct {
+		Policies []struct {
+			Active []struct {
+				Key string `json:"key"`
+			} `json:"active"`
+		} `json:"policies"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Policies) != 1 || body.Policies[0].Active[0].Key != "10.9.9.1" {
+		t.Fatalf("unexpected admin response %s (%v)", rec.Body, err)
+	}
+
+	// /metrics is unauthenticated: keys are exported hashed, as in logs.
+	hasher := limiter.NewKeyHasher([]byte("metrics-secret"))
+	manager.SetKeyHasher(hasher)
+	metrics := server.NewMetrics()
+	metrics.RegisterHeavyHitters(manager, 1)
+	scraped := scrape(t, metrics)
+	if !strings.Contains(scraped, `rate_limiter_heavy_hitter_requests{key_hash="`+hasher.Hash("10.9.9.1")+`",kind="active",policy="api"}`) {
+		t.Fatalf("expected the top key exported hashed:\n%s", scraped)
+	}
+	if strings.Contains(scraped, "10.9.9.1") {
+		t.Fatalf("expected no raw keys on /metrics:\n%s", scraped)
+	}
+	if strings.Count(scraped, "rate_limiter_heavy_hitter_requests{") != 2 {
+		t.Fatalf("expected exactly one active and one limited series:\n%s", scraped)
+	}
+}
+
+func TestHeavyHittersDecayToCurrentTraffic(t *testing.T) {
+	policy := singleRequestPolicy("api")
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	manager.EnableHeavyHitters(10, 20*time.Millisecond)
+	send := func(ip string, n int) {
+		for i := 0; i < n; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+			req.RemoteAddr = ip + ":1"
+			_, _, _, _ = manager.Allow(context.Background(), req)
+		}
+	}
+
+	send("10.9.9.1", 64)
+	time.Sleep(90 * time.Millisecond)
+	send("10.9.9.2", 32)
+
+	active := manager.HeavyHitters(1)[0].Active
+	if len(active) != 1 || active[0].Key != "10.9.9.2" {
+		t.Fatalf("expected the current client to outrank a past burst, got %+v", active)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if active := manager.HeavyHitters(10)[0].Active; len(active) != 0 {
+		t.Fatalf("expected idle clients to decay away, got %+v", active)
+	}
+}
@@ -0,0 +1,333 @@
+package tests
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func identityManager(t *testing.T, identity config.IdentityConfig) *limiter.Manager {
+	t.Helper()
+	policy := singleRequestPolicy("api")
+	policy.Routes = []string{"/api/*", "/demo.Service/*"}
+	policy.Identity = identity
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	return manager
+}
+
+// decide evaluates one request, returning whether a policy matched and the result.
+func decide(t *testing.T, manager *limiter.Manager, req *http.Request) (bool, limiter.Result) {
+	t.Helper()
+	result, _, matched, err := manager.Allow(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return matched, result
+}
+
+func verifiedRequest(t *testing.T, cert tls.Certificate) *http.Request {
+	t.Helper()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains:   [][]*x509.Certificate{{leaf}},
+	}
+	return req
+}
+
+func TestClientCertIdentityUsesVerifiedSubject(t *testing.T) {
+	ca := newTestCA(t)
+	manager := identityManager(t, config.IdentityConfig{Type: "client_cert"})
+
+	billing := ca.clientCert(t, "billing")
+	if matched, res := decide(t, manager, verifiedRequest(t, billing)); !matched || !res.Allowed {
+		t.Fatalf("expected the first request of billing to be allowed, got matched=%v %+v", matched, res)
+	}
+	if _, res := decide(t, manager, verifiedRequest(t, billing)); res.Allowed {
+		t.Fatal("expected billing to be limited by its certificate")
+	}
+	if _, res := decide(t, manager, verifiedRequest(t, ca.clientCert(t, "search"))); !res.Allowed {
+		t.Fatal("expected another certificate to have its own quota")
+	}
+
+	// A presented but unverified certificate is not an identity: the request
+	// is limited by IP instead of skipping the policy.
+	unverified := func() *http.Request {
+		req := verifiedRequest(t, billing)
+		req.TLS.VerifiedChains = nil
+		return req
+	}
+	if matched, res := decide(t, manager, unverified()); !matched || !res.Allowed {
+		t.Fatalf("expected unverified certificates to fall back to the IP quota, got matched=%v %+v", matched, res)
+	}
+	if _, res := decide(t, manager, unverified()); res.Allowed {
+		t.Fatal("expected unverified requests to share their IP's quota")
+	}
+	if _, err := limiter.NewManagerFromConfig([]config.Policy{{
+		Name:      "bad",
+		Routes:    []string{"/*"},
+		Identity:  config.IdentityConfig{Type: "client_cert", Fallback: "none"},
+		Algorithm: singleRequestPolicy("bad").Algorithm,
+	}}, storage.NewMemoryStorage()); err == nil {
+		t.Fatal("expected an unknown fallback to be rejected")
+	}
+}
+
+func TestClientCertIdentityURISAN(t *testing.T) {
+	ca := newTestCA(t)
+	manager := identityManager(t, config.IdentityConfig{Type: "client_cert", Key: "uri"})
+
+	certPEM, keyPEM := ca.issue(t, "workload")
+	cert, _ := tls.X509KeyPair(certPEM, keyPEM)
+	leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+	spiffe, _ := url.Parse("spiffe://example.org/ns/prod/sa/billing")
+	leaf.URIs = []*url.URL{spiffe}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+	if matched, _ := decide(t, manager, req); !matched {
+		t.Fatal("expected the URI SAN to identify the request")
+	}
+	if _, err := limiter.NewManagerFromConfig([]config.Policy{{
+		Name:      "bad",
+		Routes:    []string{"/*"},
+		Identity:  config.IdentityConfig{Type: "client_cert", Key: "serial"},
+		Algorithm: singleRequestPolicy("bad").Algorithm,
+	}}, storage.NewMemoryStorage()); err == nil {
+		t.Fatal("expected an unknown certificate field to be rejected")
+	}
+}
+
+func TestClientCertIdentityForGRPCPeers(t *testing.T) {
+	ca := newTestCA(t)
+	manager := identityManager(t, config.IdentityConfig{Type: "client_cert"})
+	interceptor := middleware.UnaryRateLimitInterceptor(manager, nil)
+	leaf := verifiedRequest(t, ca.clientCert(t, "billing")).TLS.VerifiedChains[0][0]
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr:     &net.TCPAddr{IP: net.ParseIP("10.7.0.1"), Port: 1},
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}},
+	})
+	info := &grpc.UnaryServerInfo{FullMethod: "/demo.Service/Call"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	if _, err := interceptor(ctx, nil, info, handler); err != nil {
+		t.Fatalf("expected the first call to pass: %v", err)
+	}
+	if _, err := interceptor(ctx, nil, info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected the peer certificate to be limited, got %v", err)
+	}
+}
+
+// jwtIssuer signs test tokens and publishes its keys as a JWKS file.
+type jwtIssuer struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+	file   string
+}
+
+func newJWTIssuer(t *testing.T) *jwtIssuer {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ec key: %v", err)
+	}
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	jwks := map[string]any{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "use": "sig",
+			"n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256",
+			"x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+	}}
+	data, _ := json.Marshal(jwks)
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	writeFile(t, file, data)
+	return &jwtIssuer{rsaKey: rsaKey, ecKey: ecKey, file: file}
+}
+
+func (i *jwtIssuer) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch alg {
+	case "RS256":
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, i.rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, i.ecKey, digest[:])
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func bearerRequest(token string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.RemoteAddr = "10.8.0.1:1"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestJWTIdentityLimitsVerifiedClaim(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	manager := identityManager(t, config.IdentityConfig{
+		Type:     "jwt",
+		Key:      "tenant_id",
+		JWKSFile: issuer.file,
+		Issuer:   "https://auth.example.com",
+		Audience: "rate-limiter",
+	})
+	claims := func(tenant string) map[string]any {
+		return map[string]any{
+			"iss":       "https://auth.example.com",
+			"aud":       []string{"rate-limiter"},
+			"exp":       time.Now().Add(time.Hour).Unix(),
+			"tenant_id": tenant,
+		}
+	}
+
+	acme := issuer.sign(t, "RS256", "rsa-1", claims("acme"))
+	if matched, res := decide(t, manager, bearerRequest(acme)); !matched || !res.Allowed {
+		t.Fatalf("expected acme's first request to be allowed, got matched=%v %+v", matched, res)
+	}
+	// The same tenant is limited even with a different token and signing key.
+	if _, res := decide(t, manager, bearerRequest(issuer.sign(t, "ES256", "ec-1", claims("acme")))); res.Allowed {
+		t.Fatal("expected acme to be limited across tokens")
+	}
+	if _, res := decide(t, manager, bearerRequest(issuer.sign(t, "ES256", "ec-1", claims("globex")))); !res.Allowed {
+		t.Fatal("expected another tenant to have its own quota")
+	}
+}
+
+func TestJWTIdentityRejectsUnverifiedTokens(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	valid := map[string]any{"sub": "alice", "aud": "rate-limiter", "exp": time.Now().Add(time.Hour).Unix()}
+
+	forger := newJWTIssuer(t)
+	b64 := base64.RawURLEncoding.EncodeToString
+	unsigned := b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{"sub":"alice","aud":"rate-limiter"}`)) + "."
+	expired := map[string]any{"sub": "alice", "aud": "rate-limiter", "exp": time.Now().Add(-time.Minute).Unix()}
+	otherAudience := map[string]any{"sub": "alice", "aud": "billing", "exp": time.Now().Add(time.Hour).Unix()}
+
+	for name, token := range map[string]string{
+		"missing":        "",
+		"garbage":        "not.a.token",
+		"forged":         forger.sign(t, "RS256", "rsa-1", valid),
+		"alg none":       unsigned,
+		"expired":        issuer.sign(t, "RS256", "rsa-1", expired),
+		"wrong audience": issuer.sign(t, "RS256", "rsa-1", otherAudience),
+		"alg confusion":  issuer.sign(t, "ES256", "rsa-1", valid),
+	} {
+		// Each rejected token is limited by IP: it spends the address's
+		// quota, never alice's, and the policy is not skipped.
+		manager := identityManager(t, config.IdentityConfig{Type: "jwt", JWKSFile: issuer.file, Audience: "rate-limiter"})
+		if matched, res := decide(t, manager, bearerRequest(token)); !matched || !res.Allowed {
+			t.Fatalf("%s token: expected the IP's first request to be allowed, got matched=%v %+v", name, matched, res)
+		}
+		if _, res := decide(t, manager, bearerRequest(issuer.sign(t, "RS256", "rsa-1", valid))); !res.Allowed {
+			t.Fatalf("%s token must not produce alice's identity", name)
+		}
+		if _, res := decide(t, manager, bearerRequest(token)); res.Allowed {
+			t.Fatalf("%s token must not bypass the policy", name)
+		}
+	}
+}
+
+func TestJWTIdentityFallbackIgnoresForwardedFor(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	manager := identityManager(t, config.IdentityConfig{Type: "jwt", JWKSFile: issuer.file})
+
+	// Anonymous requests from one connection share its bucket whatever
+	// forwarding headers they claim.
+	for i := 0; i < 5; i++ {
+		req := bearerRequest("garbage")
+		req.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i))
+		req.Header.Set("X-Real-IP", fmt.Sprintf("198.51.100.%d", i))
+		_, res := decide(t, manager, req)
+		if res.Allowed != (i == 0) {
+			t.Fatalf("request %d: expected only the first forged request to be allowed, got %+v", i, res)
+		}
+	}
+	other := bearerRequest("garbage")
+	other.RemoteAddr = "10.8.0.2:1"
+	if _, res := decide(t, manager, other); !res.Allowed {
+		t.Fatal("expected another connection to have its own quota")
+	}
+}
+
+func TestJWTIdentityFallsBackToIP(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	manager := identityManager(t, config.IdentityConfig{Type: "jwt", JWKSFile: issuer.file, Fallback: "ip"})
+
+	if _, res := decide(t, manager, bearerRequest("forged")); !res.Allowed {
+		t.Fatal("expected the first anonymous request to be allowed")
+	}
+	if _, res := decide(t, manager, bearerRequest("")); res.Allowed {
+		t.Fatal("expected anonymous requests to share their IP's quota")
+	}
+	if _, err := limiter.NewManagerFromConfig([]config.Policy{{
+		Name:      "bad",
+		Routes:    []string{"/*"},
+		Identity:  config.IdentityConfig{Type: "jwt", JWKSFile: filepath.Join(t.TempDir(), "missing.json")},
+		Algorithm: singleRequestPolicy("bad").Algorithm,
+	}}, storage.NewMemoryStorage()); err == nil {
+		t.Fatal("expected a missing JWKS file to be rejected")
+	}
+	if _, err := limiter.NewManagerFromConfig([]config.Policy{{
+		Name:      "bad",
+		Routes:    []string{"/*"},
+		Identity:  config.IdentityConfig{Type: "jwt", JWKSFile: issuer.file, Fallback: "deny"},
+		Algorithm: singleRequestPolicy("bad").Algorithm,
+	}}, storage.NewMemoryStorage()); err == nil {
+		t.Fatal("expected an unknown fallback to be rejected")
+	}
+}
@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/jwt"
+)
+
+// rawToken assembles a token from a header, claims and signature as given.
+func rawToken(header, claims map[string]any, signature []byte) string {
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	b64 := base64.RawURLEncoding.EncodeToString
+	return b64(h) + "." + b64(c) + "." + b64(signature)
+}
+
+// hmacToken signs header and claims with HMAC-SHA256 under secret.
+func hmacToken(header, claims map[string]any, secret []byte) string {
+	unsigned := strings.TrimSuffix(rawToken(header, claims, nil), ".")
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// resign replaces the signature of token.
+func resign(token string, signature []byte) string {
+	return token[:strings.LastIndex(token, ".")+1] + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func signature(t *testing.T, token string) []byte {
+	t.Helper()
+	sig, err := base64.RawURLEncoding.DecodeString(token[strings.LastIndex(token, ".")+1:])
+	if err != nil {
+		t.Fatalf("decode signature: %v", err)
+	}
+	return sig
+}
+
+func newVerifier(t *testing.T, cfg jwt.Config) *jwt.Verifier {
+	t.Helper()
+	verifier, err := jwt.NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("verifier: %v", err)
+	}
+	return verifier
+}
+
+func validClaims() map[string]any {
+	return map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func expectRejected(t *testing.T, verifier *jwt.Verifier, name, token string) {
+	t.Helper()
+	if _, err := verifier.Verify(token); !errors.Is(err, jwt.ErrInvalidToken) {
+		t.Fatalf("%s: expected ErrInvalidToken, got %v", name, err)
+	}
+}
+
+func TestJWTRejectsAlgorithmAndKeyTypeConfusion(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	verifier := newVerifier(t, jwt.Config{JWKSFile: issuer.file})
+
+	if claims, err := verifier.Verify(issuer.sign(t, "ES256", "ec-1", validClaims())); err != nil || claims["sub"] != "alice" {
+		t.Fatalf("expected a valid ES256 token to verify, got %v %v", claims, err)
+	}
+	// Each algorithm only applies to its own key type.
+	es := issuer.sign(t, "ES256", "ec-1", validClaims())
+	expectRejected(t, verifier, "ES256 signature for the RSA key", rawToken(
+		map[string]any{"alg": "ES256", "kid": "rsa-1"}, validClaims(), signature(t, es)))
+	rs := issuer.sign(t, "RS256", "rsa-1", validClaims())
+	expectRejected(t, verifier, "RS256 signature for the EC key", rawToken(
+		map[string]any{"alg": "RS256", "kid": "ec-1"}, validClaims(), signature(t, rs)))
+	// The RSA key is pinned to RS256 by its alg, so PS256 is refused.
+	expectRejected(t, verifier, "PS256 for an RS256 key", rawToken(
+		map[string]any{"alg": "PS256", "kid": "rsa-1"}, validClaims(), signature(t, rs)))
+}
+
+func TestJWTRejectsNoneAndHMAC(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	verifier := newVerifier(t, jwt.Config{JWKSFile: issuer.file})
+
+	for _, alg := range []string{"none", "None", "NONE", ""} {
+		expectRejected(t, verifier, "alg "+alg, rawToken(map[string]any{"alg": alg}, validClaims(), nil))
+		expectRejected(t, verifier, "alg "+alg+" with kid", rawToken(map[string]any{"alg": alg, "kid": "rsa-1"}, validClaims(), nil))
+	}
+
+	// The classic confusion attack: HMAC keyed with the public RSA key.
+	der, err := x509.MarshalPKIXPublicKey(&issuer.rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	for _, secret := range [][]byte{publicPEM, der, issuer.rsaKey.N.Bytes()} {
+		expectRejected(t, verifier, "HS256 keyed with the RSA key", hmacToken(map[string]any{"alg": "HS256", "kid": "rsa-1"}, validClaims(), secret))
+	}
+
+	// Symmetric keys in the JWKS are ignored rather than trusted.
+	b64 := base64.RawURLEncoding.EncodeToString
+	data, _ := json.Marshal(map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "shared", "k": b64([]byte("secret"))},
+		{"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "n": b64(issuer.rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(issuer.rsaKey.E)).Bytes())},
+	}})
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	writeFile(t, file, data)
+	withOct := newVerifier(t, jwt.Config{JWKSFile: file})
+	expectRejected(t, withOct, "HS256 with an oct key", hmacToken(map[string]any{"alg": "HS256", "kid": "shared"}, validClaims(), []byte("secret")))
+
+	octOnly, _ := json.Marshal(map[string]any{"keys": []map[string]string{{"kty": "oct", "k": b64([]byte("secret"))}}})
+	writeFile(t, file, octOnly)
+	if _, err := jwt.NewVerifier(jwt.Config{JWKSFile: file}); err == nil {
+		t.Fatal("expected a JWKS without signature keys to be rejected")
+	}
+}
+
+func TestJWTRequiresFixedLengthECSignatures(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	verifier := newVerifier(t, jwt.Config{JWKSFile: issuer.file})
+
+	token := issuer.sign(t, "ES256", "ec-1", validClaims())
+	sig := signature(t, token)
+	r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+	der, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatalf("marshal signature: %v", err)
+	}
+
+	expectRejected(t, verifier, "ASN.1 signature", resign(token, der))
+	expectRejected(t, verifier, "truncated signature", resign(token, sig[:63]))
+	expectRejected(t, verifier, "padded signature", resign(token, append([]byte{0}, sig...)))
+	expectRejected(t, verifier, "empty signature", resign(token, nil))
+	if _, err := verifier.Verify(resign(token, sig)); err != nil {
+		t.Fatalf("expected the raw 64-byte signature to verify: %v", err)
+	}
+}
+
+func TestJWTMatchesKeysByKid(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	verifier := newVerifier(t, jwt.Config{JWKSFile: issuer.file})
+
+	expectRejected(t, verifier, "unknown kid", issuer.sign(t, "RS256", "rsa-2", validClaims()))
+	expectRejected(t, verifier, "kid of another key", issuer.sign(t, "ES256", "rsa-1", validClaims()))
+	// Without a kid every key that fits the algorithm is tried.
+	if _, err := verifier.Verify(issuer.sign(t, "RS256", "", validClaims())); err != nil {
+		t.Fatalf("expected a token without kid to verify: %v", err)
+	}
+}
+
+func TestJWTRequiresExpiry(t *testing.T) {
+	issuer := newJWTIssuer(t)
+	claims := map[string]any{"sub": "alice"}
+	token := issuer.sign(t, "RS256", "rsa-1", claims)
+
+	expectRejected(t, newVerifier(t, jwt.Config{JWKSFile: issuer.file}), "missing exp", token)
+	if _, err := newVerifier(t, jwt.Config{JWKSFile: issuer.file, AllowMissingExp: true}).Verify(token); err != nil {
+		t.Fatalf("expected allow_missing_exp to accept the token: %v", err)
+	}
+
+	expired := map[string]any{"sub": "alice", "exp": time.Now().Add(-10 * time.Second).Unix()}
+	expectRejected(t, newVerifier(t, jwt.Config{JWKSFile: issuer.file, AllowMissingExp: true}), "expired", issuer.sign(t, "RS256", "rsa-1", expired))
+	if _, err := newVerifier(t, jwt.Config{JWKSFile: issuer.file, Leeway: time.Minute}).Verify(issuer.sign(t, "RS256", "rsa-1", expired)); err != nil {
+		t.Fatalf("expected leeway to tolerate clock skew: %v", err)
+	}
+}
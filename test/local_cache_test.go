@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+// countingStorage counts round trips to the wrapped store.
+type countingStorage struct {
+	*storage.MemoryStorage
+	ops atomic.Int64
+}
+
+func (c *countingStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	c.ops.Add(1)
+	return c.MemoryStorage.Get(ctx, key)
+}
+
+func (c *countingStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.ops.Add(1)
+	return c.MemoryStorage.Set(ctx, key, value, ttl)
+}
+
+func localCachePolicy(limit, maxLocal int) []config.Policy {
+	return []config.Policy{{
+		Name: "hot",
+		Algorithm: config.AlgorithmConfig{
+			Type:   string(limiter.AlgorithmSlidingWindow),
+			Limit:  limit,
+			Window: config.Duration(time.Minute),
+		},
+		LocalCache: config.LocalCacheConfig{
+			Enabled:      true,
+			SyncInterval: config.Duration(time.Hour),
+			MaxLocal:     maxLocal,
+		},
+	}}
+}
+
+func TestLocalCacheCutsStorageRoundTrips(t *testing.T) {
+	store := &countingStorage{MemoryStorage: storage.NewMemoryStorage()}
+	manager, err := limiter.NewManagerFromConfig(localCachePolicy(10000, 100), store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	defer manager.Close()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for i := 0; i < 1000; i++ {
+		res, _, _, err := manager.Allow(context.Background(), req)
+		if err != nil || !res.Allowed {
+			t.Fatalf("request %d should be allowed, err=%v", i+1, err)
+		}
+	}
+	if ops := store.ops.Load(); ops > 40 {
+		t.Fatalf("expected roughly one sync per 100 requests, got %d storage ops", ops)
+	}
+}
+
+func TestLocalCacheBoundsOverAdmission(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	const limit, maxLocal = 20, 5
+
+	var replicas []*limiter.Manager
+	for i := 0; i < 3; i++ {
+		manager, err := limiter.NewManagerFromConfig(localCachePolicy(limit, maxLocal), store)
+		if err != nil {
+			t.Fatalf("failed to build manager: %v", err)
+		}
+		defer manager.Close()
+		replicas = append(replicas, manager)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	admitted := 0
+	for i := 0; i < 200; i++ {
+		res, _, _, err := replicas[i%len(replicas)].Allow(context.Background(), req)
+		if err != nil {
+			t.Fatalf("allow failed: %v", err)
+		}
+		if res.Allowed {
+			admitted++
+		}
+	}
+	if max := limit + (len(replicas)-1)*maxLocal; admitted > max {
+		t.Fatalf("admitted %d requests, over-admission bound is %d", admitted, max)
+	}
+	if admitted < limit/2 {
+		t.Fatalf("admitted only %d requests", admitted)
+	}
+}
+
+func TestLocalCacheFlushesOnClose(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	manager, err := limiter.NewManagerFromConfig(localCachePolicy(10, 100), store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for i := 0; i < 6; i++ {
+		_, _, _, _ = manager.Allow(context.Background(), req)
+	}
+	if err := manager.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	direct := limiter.NewSlidingWindowLimiter(store, 10, time.Minute, "sw:hot")
+	res, err := direct.Allow(context.Background(), "192.0.2.1")
+	if err != nil {
+		t.Fatalf("allow failed: %v", err)
+	}
+	if res.Remaining != 3 {
+		t.Fatalf("expected flushed state to leave 3 requests, got %d", res.Remaining)
+	}
+}
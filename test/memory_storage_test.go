@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func TestMemoryStorageJanitorSweepsExpired(t *testing.T) {
+	store := storage.NewMemoryStorageWithConfig(storage.MemoryConfig{SweepInterval: 10 * time.Millisecond})
+	defer store.Close()
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c"} {
+		_ = store.Set(ctx, key, []byte("v"), 5*time.Millisecond)
+	}
+	_ = store.Set(ctx, "keep", []byte("v"), time.Minute)
+
+	deadline := time.Now().Add(time.Second)
+	for store.Len() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor did not sweep expired keys, %d left", store.Len())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMemoryStorageLRUEviction(t *testing.T) {
+	store := storage.NewMemoryStorageWithConfig(storage.MemoryConfig{MaxEntries: 2, Eviction: storage.EvictLRU, Shards: 1})
+	ctx := context.Background()
+
+	_ = store.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = store.Set(ctx, "b", []byte("2"), time.Minute)
+	if _, err := store.Get(ctx, "a"); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	_ = store.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, err := store.Get(ctx, "b"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("least recently used key should be evicted, got %v", err)
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, err := store.Get(ctx, key); err != nil {
+			t.Fatalf("key %s should survive: %v", key, err)
+		}
+	}
+	if store.Evictions() != 1 {
+		t.Fatalf("expected 1 eviction, got %d", store.Evictions())
+	}
+}
+
+func TestMemoryStorageRandomEvictionBoundsSize(t *testing.T) {
+	store := storage.NewMemoryStorageWithConfig(storage.MemoryConfig{MaxEntries: 10, Eviction: storage.EvictRandom, Shards: 1})
+	ctx := context.Background()
+
+	for i := 0; i < 100; i++ {
+		_ = store.Set(ctx, fmt.Sprintf("key-%d", i), []byte("v"), time.Minute)
+	}
+	if store.Len() != 10 {
+		t.Fatalf("expected size capped at 10, got %d", store.Len())
+	}
+	if store.Evictions() != 90 {
+		t.Fatalf("expected 90 evictions, got %d", store.Evictions())
+	}
+}
+
+func TestShardedMemoryStorageRespectsBounds(t *testing.T) {
+	store := storage.NewMemoryStorageWithConfig(storage.MemoryConfig{MaxEntries: 64, Shards: 8})
+	ctx := context.Background()
+
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("key-%d", i)
+		if err := store.Set(ctx, key, []byte(key), time.Minute); err != nil {
+			t.Fatalf("set failed: %v", err)
+		}
+		got, err := store.Get(ctx, key)
+		if err != nil || string(got) != key {
+			t.Fatalf("expected %s to be readable right after set, got %q err=%v", key, got, err)
+		}
+	}
+	if n := store.Len(); n > 64 {
+		t.Fatalf("expected at most 64 entries, got %d", n)
+	}
+	if n := store.Len() + int(store.Evictions()); n != 1000 {
+		t.Fatalf("entries plus evictions should account for every key, got %d", n)
+	}
+}
+
+func TestMemoryStorageScanVisitsEveryShard(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorageWithConfig(storage.MemoryConfig{Shards: 8})
+	for i := 0; i < 2500; i++ {
+		_ = store.Set(ctx, fmt.Sprintf("tb:api:%d", i), []byte("x"), time.Minute)
+	}
+	_ = store.Set(ctx, "tb:other:1", []byte("x"), time.Minute)
+	_ = store.Set(ctx, "tb:api:expired", []byte("x"), time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	seen := make(map[string]bool)
+	calls := 0
+	err := storage.ScanAll(ctx, store, "tb:api:", func(keys []string) error {
+		calls++
+		for _, key := range keys {
+			seen[key] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(seen) != 2500 || seen["tb:api:expired"] || seen["tb:other:1"] {
+		t.Fatalf("expected exactly the 2500 live keys under the prefix, got %d", len(seen))
+	}
+	if calls < 2 {
+		t.Fatalf("expected the scan to be split in batches, got %d", calls)
+	}
+}
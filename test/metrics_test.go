@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/server"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func scrape(t *testing.T, metrics *server.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestMetricsRecordDecisionAndStorageLatency(t *testing.T) {
+	metrics := server.NewMetrics()
+	store := storage.NewInstrumentedStorage(storage.NewMemoryStorage(), "memory", metrics)
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("api")}, store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	manager.SetDecisionObserver(metrics)
+
+	_, _, _, _ = manager.Allow(context.Background(), httptest.NewRequest(http.MethodGet, "/api/items", nil))
+
+	body := scrape(t, metrics)
+	for _, want := range []string{
+		`rate_limiter_decision_duration_seconds_count{algorithm="sliding_window",policy="api"} 1`,
+		`rate_limiter_storage_operation_duration_seconds_count{driver="memory",operation="get"} 1`,
+		`rate_limiter_storage_operation_duration_seconds_count{driver="memory",operation="set"} 1`,
+		`rate_limiter_build_info{`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %s in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "rate_limiter_storage_operation_errors_total{") {
+		t.Fatalf("a missing key must not count as a storage error:\n%s", body)
+	}
+}
+
+func TestMetricsCountStorageOperationErrors(t *testing.T) {
+	metrics := server.NewMetrics()
+	store := storage.NewInstrumentedStorage(failingStorage{}, "redis", metrics)
+	_, _ = store.Get(context.Background(), "k")
+	_ = store.Set(context.Background(), "k", nil, time.Second)
+
+	body := scrape(t, metrics)
+	if !strings.Contains(body, `rate_limiter_storage_operation_errors_total{driver="redis",operation="get"} 1`) ||
+		!strings.Contains(body, `rate_limiter_storage_operation_errors_total{driver="redis",operation="set"} 1`) {
+		t.Fatalf("expected storage errors per operation:\n%s", body)
+	}
+}
+
+func TestMetricsConfigAndWatchedKeyInfo(t *testing.T) {
+	metrics := server.NewMetrics()
+	metrics.SetConfigInfo("abc123", "memory")
+	metrics.SetPolicyInfo("api", "sliding_window", "shadow")
+	metrics.ObserveWatchedKey("api", "partner", 42)
+
+	body := scrape(t, metrics)
+	for _, want := range []string{
+		`rate_limiter_config_info{config_hash="abc123",storage_driver="memory"} 1`,
+		`rate_limiter_policy_info{algorithm="sliding_window",mode="shadow",policy="api"} 1`,
+		`rate_limiter_watched_key_remaining{key="partner",policy="api"} 42`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %s in:\n%s", want, body)
+		}
+	}
+}
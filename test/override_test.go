@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/admin"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func allowedCount(t *testing.T, manager *limiter.Manager, remoteAddr string, n int) int {
+	t.Helper()
+	allowed := 0
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.RemoteAddr = remoteAddr
+		res, _, _, err := manager.Allow(context.Background(), req)
+		if err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestOverrideScalesLimitsAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	policy := singleRequestPolicy("api")
+	policy.Algorithm.Limit = 2
+	store := storage.NewMemoryStorage()
+
+	replicaA, err := limiter.NewManagerFromConfig([]config.Policy{policy}, store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	replicaB, err := limiter.NewManagerFromConfig([]config.Policy{policy}, store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+
+	err = replicaA.SetOverride(ctx, limiter.Override{
+		Policy:    "api",
+		Key:       "10.3.0.1",
+		Scale:     10,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("set override: %v", err)
+	}
+	if err := replicaB.RefreshOverrides(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	if got := allowedCount(t, replicaB, "10.3.0.1:1", 25); got != 20 {
+		t.Fatalf("expected the overridden key to get 10x the limit, got %d", got)
+	}
+	if got := allowedCount(t, replicaB, "10.3.0.2:1", 5); got != 2 {
+		t.Fatalf("other keys must keep the configured limit, got %d", got)
+	}
+
+	if err := replicaA.DeleteOverride(ctx, "api", "10.3.0.1"); err != nil {
+		t.Fatalf("delete override: %v", err)
+	}
+	if err := replicaB.RefreshOverrides(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if got := allowedCount(t, replicaB, "10.3.0.1:1", 3); got != 2 {
+		t.Fatalf("expected the configured limit once the override is gone, got %d", got)
+	}
+}
+
+func TestOverrideExpires(t *testing.T) {
+	ctx := context.Background()
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("api")}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	err = manager.SetOverride(ctx, limiter.Override{
+		Policy:    "api",
+		Key:       "10.3.1.1",
+		Limit:     5,
+		Window:    time.Minute,
+		ExpiresAt: time.Now().Add(20 * time.Millisecond),
+	})
+	if err != nil {
+		t.Fatalf("set override: %v", err)
+	}
+	if got := allowedCount(t, manager, "10.3.1.1:1", 6); got != 5 {
+		t.Fatalf("expected the override limit, got %d", got)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if got := allowedCount(t, manager, "10.3.1.1:1", 2); got != 1 {
+		t.Fatalf("expected the configured limit after expiry, got %d", got)
+	}
+	if overrides, _ := manager.Overrides(ctx); len(overrides) != 0 {
+		t.Fatalf("expired overrides must not be listed, got %v", overrides)
+	}
+}
+
+func TestAdminOverrideEndpoints(t *testing.T) {
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("api")}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	handler := admin.NewHandler("secret")
+	handler.HandleOverrides(manager)
+
+	rec := adminRequest(handler, http.MethodPut, "/admin/overrides/api/10.3.2.1", `{"limit":3,"window":"1s","ttl":"1h","reason":"incident"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("put: expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = adminRequest(handler, http.MethodGet, "/admin/overrides", "")
+	if !strings.Contains(rec.Body.String(), `"window":"1s"`) || !strings.Contains(rec.Body.String(), `"reason":"incident"`) {
+		t.Fatalf("expected the override listed, got %s", rec.Body)
+	}
+	if rec := adminRequest(handler, http.MethodPut, "/admin/overrides/api/x", `{"ttl":"1h"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without scale or limit, got %d", rec.Code)
+	}
+	if rec := adminRequest(handler, http.MethodPut, "/admin/overrides/missing/x", `{"scale":2,"ttl":"1h"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown policy, got %d", rec.Code)
+	}
+	if rec := adminRequest(handler, http.MethodDelete, "/admin/overrides/api/10.3.2.1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("delete: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestAdminKeysFollowOverrides(t *testing.T) {
+	ctx := context.Background()
+	policy := singleRequestPolicy("api")
+	policy.Algorithm.Limit = 2
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	if err := manager.SetOverride(ctx, limiter.Override{Policy: "api", Key: "10.3.0.1", Scale: 5, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("set override: %v", err)
+	}
+
+	if got := allowedCount(t, manager, "10.3.0.1:1", 12); got != 10 {
+		t.Fatalf("expected the override's limit of 10, got %d", got)
+	}
+	state, err := manager.InspectKey(ctx, "api", "10.3.0.1")
+	if err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	if !state.Exists || state.Result.Limit != 10 || state.Remaining != 0 || !strings.Contains(state.StateKey, "~override") {
+		t.Fatalf("expected inspect to show the override's exhausted bucket, got %+v", state)
+	}
+
+	if err := manager.GrantQuota(ctx, "api", "10.3.0.1", 3); err != nil {
+		t.Fatalf("grant: %v", err)
+	}
+	if got := allowedCount(t, manager, "10.3.0.1:1", 5); got != 3 {
+		t.Fatalf("expected the grant to apply to the overridden key, got %d", got)
+	}
+	if err := manager.ResetKey(ctx, "api", "10.3.0.1"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if got := allowedCount(t, manager, "10.3.0.1:1", 12); got != 10 {
+		t.Fatalf("expected reset to refill the override's bucket, got %d", got)
+	}
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/admin"
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func penaltyPolicy(ban time.Duration) config.Policy {
+	policy := singleRequestPolicy("api")
+	policy.Penalty = config.PenaltyConfig{
+		Threshold:   3,
+		Window:      config.Duration(time.Minute),
+		BanDuration: config.Duration(ban),
+	}
+	return policy
+}
+
+func TestPenaltyBoxBansAcrossReplicas(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	policies := []config.Policy{penaltyPolicy(time.Minute)}
+	replicaA, err := limiter.NewManagerFromConfig(policies, store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	replicaB, err := limiter.NewManagerFromConfig(policies, store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	handler := middleware.RateLimiter(replicaA, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	statuses := make([]int, 0, 5)
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 5; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.RemoteAddr = "10.4.0.1:1"
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		statuses = append(statuses, rec.Code)
+	}
+	// One admitted request, three rejections trigger the ban, then banned.
+	want := []int{200, 429, 429, 429, 429}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("expected status sequence %v, got %v", want, statuses)
+		}
+	}
+	if !strings.Contains(rec.Body.String(), "banned") || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected the ban response with Retry-After until the ban ends, got %q (Retry-After %q)",
+			rec.Body.String(), rec.Header().Get("Retry-After"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.RemoteAddr = "10.4.0.1:1"
+	res, _, _, err := replicaB.Allow(context.Background(), req)
+	if err != nil || !res.Banned || res.RetryAfter <= 0 {
+		t.Fatalf("expected the other replica to enforce the ban, got %+v (%v)", res, err)
+	}
+}
+
+func TestPenaltyBoxEscalatesAndCanBeCleared(t *testing.T) {
+	ctx := context.Background()
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{penaltyPolicy(20 * time.Millisecond)}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	hammer := func() limiter.Result {
+		var res limiter.Result
+		for i := 0; i < 4; i++ {
+			req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+			req.RemoteAddr = "10.4.1.1:1"
+			res, _, _, _ = manager.Allow(ctx, req)
+		}
+		return res
+	}
+
+	first := hammer()
+	if first.RetryAfter != 20*time.Millisecond {
+		t.Fatalf("expected the first ban to last ban_duration, got %v", first.RetryAfter)
+	}
+	time.Sleep(25 * time.Millisecond)
+	second := hammer()
+	if second.RetryAfter <= 20*time.Millisecond || second.RetryAfter > 40*time.Millisecond {
+		t.Fatalf("expected the second ban to double, got %v", second.RetryAfter)
+	}
+
+	handler := admin.NewHandler("secret")
+	handler.HandleBans(manager)
+	rec := adminRequest(handler, http.MethodGet, "/admin/bans", "")
+	if !strings.Contains(rec.Body.String(), `"key":"10.4.1.1"`) || !strings.Contains(rec.Body.String(), `"strikes":2`) {
+		t.Fatalf("expected the ban listed, got %s", rec.Body)
+	}
+	if rec := adminRequest(handler, http.MethodDelete, "/admin/bans/api/10.4.1.1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("clear: expected 200, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.RemoteAddr = "10.4.1.1:1"
+	if res, _, _, _ := manager.Allow(ctx, req); res.Banned {
+		t.Fatal("cleared key must not be banned")
+	}
+}
+
+// headerStream captures the metadata an interceptor sets with grpc.SetHeader.
+type headerStream struct {
+	header metadata.MD
+}
+
+func (s *headerStream) Method() string { return "" }
+func (s *headerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+func (s *headerStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+func (s *headerStream) SetTrailer(metadata.MD) error    { return nil }
+
+func TestBansAnswerResourceExhaustedWithRetryAfter(t *testing.T) {
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{penaltyPolicy(time.Minute)}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	handler := middleware.RateLimiter(manager, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	exhaust(handler, "/api/items", 4)
+
+	interceptor := middleware.UnaryRateLimitInterceptor(manager, nil)
+	stream := &headerStream{}
+	ctx := grpc.NewContextWithServerTransportStream(peerContext(), stream)
+	_, err = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/api/items"},
+		func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	if st := status.Convert(err); st.Code() != codes.ResourceExhausted || !strings.Contains(st.Message(), "banned") {
+		t.Fatalf("expected RESOURCE_EXHAUSTED for a banned key, got %v", err)
+	}
+	if got := stream.header.Get("retry-after"); len(got) != 1 || got[0] != "60" {
+		t.Fatalf("expected retry-after metadata until the ban ends, got %v", got)
+	}
+}
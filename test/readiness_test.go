@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/server"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// pingStorage is a memory store whose Ping runs fn.
+type pingStorage struct {
+	*storage.MemoryStorage
+	fn func(ctx context.Context) error
+}
+
+func (s pingStorage) Ping(ctx context.Context) error {
+	return s.fn(ctx)
+}
+
+func readyz(t *testing.T, readiness *server.Readiness) (int, server.ReadinessReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	readiness.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report server.ReadinessReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("invalid readiness body %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, report
+}
+
+func TestReadinessRequiresLoadedConfig(t *testing.T) {
+	readiness := server.NewReadiness(storage.NewMemoryStorage(), time.Second)
+	if code, report := readyz(t, readiness); code != http.StatusServiceUnavailable || report.Checks["config"] != "not loaded" {
+		t.Fatalf("expected not ready before the config is loaded, got %d %v", code, report)
+	}
+
+	readiness.MarkConfigLoaded()
+	if code, report := readyz(t, readiness); code != http.StatusOK || !report.Ready || report.Checks["storage"] != "ok" {
+		t.Fatalf("expected ready, got %d %v", code, report)
+	}
+}
+
+func TestReadinessReflectsStoragePing(t *testing.T) {
+	pingErr := errors.New("connection refused")
+	store := pingStorage{MemoryStorage: storage.NewMemoryStorage(), fn: func(context.Context) error { return pingErr }}
+	readiness := server.NewReadiness(storage.NewInstrumentedStorage(store, "redis", nil), time.Second)
+	readiness.MarkConfigLoaded()
+
+	code, report := readyz(t, readiness)
+	if code != http.StatusServiceUnavailable || report.Checks["storage"] != "connection refused" {
+		t.Fatalf("expected the storage failure to be reported, got %d %v", code, report)
+	}
+
+	pingErr = nil
+	if code, _ := readyz(t, readiness); code != http.StatusOK {
+		t.Fatalf("expected ready once storage recovers, got %d", code)
+	}
+}
+
+func TestReadinessBoundsStoragePing(t *testing.T) {
+	store := pingStorage{MemoryStorage: storage.NewMemoryStorage(), fn: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	readiness := server.NewReadiness(store, 20*time.Millisecond)
+	readiness.MarkConfigLoaded()
+
+	start := time.Now()
+	report := readiness.Check(context.Background())
+	if report.Ready || time.Since(start) > time.Second {
+		t.Fatalf("expected a hung storage to fail the check quickly, got %v after %s", report, time.Since(start))
+	}
+}
+
+func TestReadinessUnreachableRedis(t *testing.T) {
+	store := storage.NewRedisStorage(storage.RedisConfig{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond})
+	defer store.Close()
+	readiness := server.NewReadiness(store, time.Second)
+	readiness.MarkConfigLoaded()
+
+	if report := readiness.Check(context.Background()); report.Ready {
+		t.Fatalf("expected an unreachable redis to fail readiness, got %v", report)
+	}
+}
+
+func TestReadinessDegradedWhileFallbackServes(t *testing.T) {
+	pingErr := errors.New("connection refused")
+	primary := pingStorage{MemoryStorage: storage.NewMemoryStorage(), fn: func(context.Context) error { return pingErr }}
+	store := storage.NewResilientStorage(primary, storage.ResilientConfig{})
+	defer store.Close()
+	readiness := server.NewReadiness(store, time.Second)
+	readiness.MarkConfigLoaded()
+
+	code, report := readyz(t, readiness)
+	if code != http.StatusOK || !report.Ready || report.Checks["storage"] != "degraded: connection refused" {
+		t.Fatalf("expected ready with degraded storage while the fallback serves, got %d %v", code, report)
+	}
+	pingErr = nil
+	if _, report := readyz(t, readiness); report.Checks["storage"] != "ok" {
+		t.Fatalf("expected storage ok once the primary recovers, got %v", report)
+	}
+}
+
+func TestReadinessDrainUpdatesGRPCHealth(t *testing.T) {
+	readiness := server.NewReadiness(storage.NewMemoryStorage(), time.Second)
+	readiness.MarkConfigLoaded()
+	healthServer := health.NewServer()
+
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("health check failed: %v", err)
+		}
+		return resp.Status
+	}
+
+	readiness.UpdateHealth(context.Background(), healthServer)
+	if got := status(); got != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %v", got)
+	}
+
+	readiness.Drain()
+	if code, report := readyz(t, readiness); code != http.StatusServiceUnavailable || report.Checks["draining"] != "shutting down" {
+		t.Fatalf("expected not ready while draining, got %d %v", code, report)
+	}
+	readiness.UpdateHealth(context.Background(), healthServer)
+	if got := status(); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected NOT_SERVING while draining, got %v", got)
+	}
+}
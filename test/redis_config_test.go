@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func TestRedisConfigValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		cfg     storage.RedisConfig
+		wantErr bool
+	}{
+		{"standalone", storage.RedisConfig{Addr: "localhost:6379"}, false},
+		{"standalone without address", storage.RedisConfig{}, true},
+		{"standalone with several addresses", storage.RedisConfig{Addrs: []string{"r1:6379", "r2:6379"}}, true},
+		{"standalone with address and addresses", storage.RedisConfig{Addr: "r1:6379", Addrs: []string{"r2:6379"}}, true},
+		{"sentinel", storage.RedisConfig{Mode: "sentinel", MasterName: "mymaster", Addrs: []string{"s1:26379"}}, false},
+		{"sentinel without master", storage.RedisConfig{Mode: "sentinel", Addrs: []string{"s1:26379"}}, true},
+		{"cluster", storage.RedisConfig{Mode: "cluster", Addrs: []string{"n1:6379", "n2:6379"}}, false},
+		{"cluster without seeds", storage.RedisConfig{Mode: "cluster"}, true},
+		{"unknown mode", storage.RedisConfig{Mode: "ring", Addr: "localhost:6379"}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// taggedStorage records keys and asks for hash tags like Redis Cluster.
+type taggedStorage struct {
+	*storage.MemoryStorage
+	mu   sync.Mutex
+	keys []string
+}
+
+func (s *taggedStorage) UsesHashTags() bool { return true }
+
+func (s *taggedStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	s.keys = append(s.keys, key)
+	s.mu.Unlock()
+	return s.MemoryStorage.Set(ctx, key, value, ttl)
+}
+
+func TestStateKeysAreHashTaggedOnShardedStores(t *testing.T) {
+	store := &taggedStorage{MemoryStorage: storage.NewMemoryStorage()}
+	tb := limiter.NewTokenBucketLimiter(store, 1, 1, time.Second, "tb:policy")
+
+	if _, err := tb.Allow(context.Background(), "10.0.0.1"); err != nil {
+		t.Fatalf("allow failed: %v", err)
+	}
+	if len(store.keys) != 1 || store.keys[0] != "tb:policy:{10.0.0.1}" {
+		t.Fatalf("expected hash tagged key, got %v", store.keys)
+	}
+}
+
+func TestHashTagEscapesBraces(t *testing.T) {
+	store := &taggedStorage{MemoryStorage: storage.NewMemoryStorage()}
+	for _, identity := range []string{"plain", "a}b", "{x}", "50%{"} {
+		tagged := storage.HashTag(store, identity)
+		if strings.Count(tagged, "{") != 1 || strings.Count(tagged, "}") != 1 {
+			t.Fatalf("identity %q produced a tag with extra braces: %q", identity, tagged)
+		}
+		if got := storage.HashUntag(store, tagged); got != identity {
+			t.Fatalf("HashUntag(%q) = %q, want %q", tagged, got, identity)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+// flakyStorage delegates to a MemoryStorage unless down is set.
+type flakyStorage struct {
+	*storage.MemoryStorage
+	down atomic.Bool
+}
+
+func (f *flakyStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	if f.down.Load() {
+		return nil, errStorageDown
+	}
+	return f.MemoryStorage.Get(ctx, key)
+}
+
+func (f *flakyStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if f.down.Load() {
+		return errStorageDown
+	}
+	return f.MemoryStorage.Set(ctx, key, value, ttl)
+}
+
+func newResilient(resync storage.ResyncPolicy) (*storage.ResilientStorage, *flakyStorage) {
+	primary := &flakyStorage{MemoryStorage: storage.NewMemoryStorage()}
+	return storage.NewResilientStorage(primary, storage.ResilientConfig{
+		Timeout:          50 * time.Millisecond,
+		FailureThreshold: 2,
+		OpenTimeout:      30 * time.Millisecond,
+		Resync:           resync,
+	}), primary
+}
+
+func TestResilientStorageTripsAndFallsBack(t *testing.T) {
+	ctx := context.Background()
+	store, primary := newResilient(storage.ResyncDiscard)
+	primary.down.Store(true)
+
+	for i := 0; i < 2; i++ {
+		if err := store.Set(ctx, "k", []byte("v"), time.Minute); !errors.Is(err, errStorageDown) {
+			t.Fatalf("expected primary error before the circuit trips, got %v", err)
+		}
+	}
+	if store.State() != storage.CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", store.State())
+	}
+
+	if err := store.Set(ctx, "k", []byte("local"), time.Minute); err != nil {
+		t.Fatalf("fallback set failed: %v", err)
+	}
+	got, err := store.Get(ctx, "k")
+	if err != nil || string(got) != "local" {
+		t.Fatalf("expected fallback value, got %q err=%v", got, err)
+	}
+}
+
+func TestResilientStorageRecoversAndDiscards(t *testing.T) {
+	ctx := context.Background()
+	store, primary := newResilient(storage.ResyncDiscard)
+	_ = primary.MemoryStorage.Set(ctx, "k", []byte("remote"), time.Minute)
+	primary.down.Store(true)
+	_, _ = store.Get(ctx, "k")
+	_, _ = store.Get(ctx, "k")
+	_ = store.Set(ctx, "k", []byte("local"), time.Minute)
+
+	primary.down.Store(false)
+	time.Sleep(40 * time.Millisecond)
+
+	got, err := store.Get(ctx, "k")
+	if err != nil || string(got) != "remote" {
+		t.Fatalf("expected primary value after recovery, got %q err=%v", got, err)
+	}
+	if store.State() != storage.CircuitClosed {
+		t.Fatalf("expected closed circuit, got %s", store.State())
+	}
+}
+
+func TestResilientStorageWritesBackOnRecovery(t *testing.T) {
+	ctx := context.Background()
+	store, primary := newResilient(storage.ResyncWriteBack)
+	primary.down.Store(true)
+	_, _ = store.Get(ctx, "a")
+	_, _ = store.Get(ctx, "a")
+	_ = store.Set(ctx, "b", []byte("local"), time.Minute)
+
+	primary.down.Store(false)
+	time.Sleep(40 * time.Millisecond)
+
+	if _, err := store.Get(ctx, "a"); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected probe to see missing key, got %v", err)
+	}
+	// Write-back runs in the background.
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, err := primary.MemoryStorage.Get(ctx, "b")
+		if err == nil && string(got) == "local" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected fallback entry written back, got %q err=%v", got, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	_ = store.Close()
+}
+
+func TestResilientStorageStateCallbackMayUseStore(t *testing.T) {
+	ctx := context.Background()
+	primary := &flakyStorage{MemoryStorage: storage.NewMemoryStorage()}
+	var store *storage.ResilientStorage
+	var seen atomic.Int32
+	store = storage.NewResilientStorage(primary, storage.ResilientConfig{
+		FailureThreshold: 1,
+		OnStateChange: func(from, to storage.CircuitState) {
+			// Reading the store from the callback must not deadlock.
+			if store.State() == to {
+				seen.Add(1)
+			}
+		},
+	})
+	defer store.Close()
+
+	primary.down.Store(true)
+	done := make(chan struct{})
+	go func() {
+		_, _ = store.Get(ctx, "k")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("state callback deadlocked")
+	}
+	if seen.Load() != 1 {
+		t.Fatalf("expected one transition to open, got %d", seen.Load())
+	}
+}
+
+// slowScanStorage answers Scan only after delay.
+type slowScanStorage struct {
+	*storage.MemoryStorage
+	delay time.Duration
+}
+
+func (s *slowScanStorage) Scan(ctx context.Context, prefix string, cursor uint64) ([]string, uint64, error) {
+	time.Sleep(s.delay)
+	return s.MemoryStorage.Scan(ctx, prefix, cursor)
+}
+
+func TestResilientStorageScanDoesNotTripCircuit(t *testing.T) {
+	ctx := context.Background()
+	primary := &slowScanStorage{MemoryStorage: storage.NewMemoryStorage(), delay: 30 * time.Millisecond}
+	store := storage.NewResilientStorage(primary, storage.ResilientConfig{
+		Timeout:          10 * time.Millisecond,
+		FailureThreshold: 1,
+	})
+	defer store.Close()
+	if err := store.Set(ctx, "tb:api:a", []byte("1"), time.Minute); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	keys, _, err := store.Scan(ctx, "tb:api:", 0)
+	if err != nil || len(keys) != 1 {
+		t.Fatalf("expected the slow scan to finish with 1 key, got %v (err=%v)", keys, err)
+	}
+	if state := store.State(); state != storage.CircuitClosed {
+		t.Fatalf("expected a slow scan to leave the circuit closed, got %s", state)
+	}
+}
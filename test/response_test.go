@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func newResponseTestHandler(t *testing.T, policies []config.Policy, responses config.ResponsesConfig) http.Handler {
+	t.Helper()
+	manager, err := limiter.NewManagerFromConfig(policies, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	opts, err := middleware.ResponseOptions(responses, policies)
+	if err != nil {
+		t.Fatalf("failed to build response options: %v", err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return middleware.RateLimiter(manager, nil, opts...)(ok)
+}
+
+func singleRequestPolicy(name string) config.Policy {
+	return config.Policy{
+		Name:   name,
+		Routes: []string{"/api/*"},
+		Algorithm: config.AlgorithmConfig{
+			Type:   string(limiter.AlgorithmSlidingWindow),
+			Limit:  1,
+			Window: config.Duration(time.Minute),
+		},
+	}
+}
+
+func exhaust(handler http.Handler, path string, n int) *httptest.ResponseRecorder {
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < n; i++ {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	return rec
+}
+
+func TestDefaultRejectionIsProblemJSON(t *testing.T) {
+	handler := newResponseTestHandler(t, []config.Policy{singleRequestPolicy("strict")}, config.ResponsesConfig{})
+
+	rec := exhaust(handler, "/api/orders", 2)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	var problem map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body is not valid JSON: %v (%s)", err, rec.Body.String())
+	}
+	if problem["status"] != float64(429) || problem["policy"] != "strict" || problem["instance"] != "/api/orders" {
+		t.Fatalf("unexpected problem document: %v", problem)
+	}
+}
+
+func TestPolicyResponseOverride(t *testing.T) {
+	policy := singleRequestPolicy("custom")
+	policy.Response = config.ResponseConfig{
+		Status:      503,
+		ContentType: "application/json",
+		Body:        `{"policy":{{json .Policy}},"limit":{{.Limit}}}`,
+	}
+	handler := newResponseTestHandler(t, []config.Policy{policy}, config.ResponsesConfig{})
+
+	rec := exhaust(handler, "/api/orders", 2)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+	if got := rec.Body.String(); got != `{"policy":"custom","limit":1}` {
+		t.Fatalf("unexpected body %q", got)
+	}
+}
+
+func TestInvalidResponseTemplateRejected(t *testing.T) {
+	responses := config.ResponsesConfig{RateLimited: config.ResponseConfig{Body: "{{.Broken"}}
+	if _, err := middleware.ResponseOptions(responses, nil); err == nil {
+		t.Fatal("expected template parse error")
+	}
+}
+
+// peerContext is a gRPC call context from httptest's default client address.
+func peerContext() context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1}})
+}
+
+func TestDeniedResponseForBannedRequests(t *testing.T) {
+	policies := []config.Policy{penaltyPolicy(time.Minute)}
+	responses := config.ResponsesConfig{Denied: config.ResponseConfig{
+		Status:      451,
+		Body:        "banned for {{.RetryAfter}}s",
+		GRPCCode:    "unavailable",
+		GRPCMessage: "go away",
+	}}
+	manager, err := limiter.NewManagerFromConfig(policies, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	opts, err := middleware.ResponseOptions(responses, policies)
+	if err != nil {
+		t.Fatalf("failed to build response options: %v", err)
+	}
+
+	handler := middleware.RateLimiter(manager, nil, opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	rec := exhaust(handler, "/api/orders", 5)
+	if rec.Code != 451 || rec.Body.String() != "banned for 60s" {
+		t.Fatalf("expected the configured denied response, got %d %q", rec.Code, rec.Body.String())
+	}
+
+	interceptor := middleware.UnaryRateLimitInterceptor(manager, nil, opts...)
+	info := &grpc.UnaryServerInfo{FullMethod: "/api/orders"}
+	_, err = interceptor(peerContext(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil })
+	if st := status.Convert(err); st.Code() != codes.Unavailable || st.Message() != "go away" {
+		t.Fatalf("expected the configured gRPC status, got %v", err)
+	}
+}
+
+func TestGRPCResponsesAreConfigurable(t *testing.T) {
+	policy := singleRequestPolicy("strict")
+	policy.Response = config.ResponseConfig{GRPCMessage: "slow down"}
+	responses := config.ResponsesConfig{StorageError: config.ResponseConfig{GRPCCode: "UNAVAILABLE"}}
+	opts, err := middleware.ResponseOptions(responses, []config.Policy{policy})
+	if err != nil {
+		t.Fatalf("failed to build response options: %v", err)
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/api/orders"}
+
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	interceptor := middleware.UnaryRateLimitInterceptor(manager, nil, opts...)
+	_, _ = interceptor(peerContext(), nil, info, handler)
+	_, err = interceptor(peerContext(), nil, info, handler)
+	if st := status.Convert(err); st.Code() != codes.ResourceExhausted || st.Message() != "slow down" {
+		t.Fatalf("expected the policy gRPC message, got %v", err)
+	}
+
+	broken, err := limiter.NewManagerFromConfig([]config.Policy{policy}, failingStorage{})
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	interceptor = middleware.UnaryRateLimitInterceptor(broken, nil, opts...)
+	if _, err := interceptor(peerContext(), nil, info, handler); status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected the configured storage error code, got %v", err)
+	}
+
+	bad := config.ResponsesConfig{RateLimited: config.ResponseConfig{GRPCCode: "NOT_A_CODE"}}
+	if _, err := middleware.ResponseOptions(bad, nil); err == nil {
+		t.Fatal("expected an unknown gRPC code to be rejected")
+	}
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
+	"github.com/rohankarn35/rate_limiter_golang/internal/server"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func TestShadowModeAdmitsAndRecordsWouldBeDenials(t *testing.T) {
+	policy := singleRequestPolicy("new-limit")
+	policy.Mode = "shadow"
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	metrics := server.NewMetrics()
+	handler := middleware.RateLimiter(manager, metrics)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: shadow policy must not reject, got %d", i+1, rec.Code)
+		}
+		if rec.Header().Get("X-RateLimit-Limit") != "" || rec.Header().Get("Retry-After") != "" {
+			t.Fatalf("request %d: shadow policy must not send rate limit headers", i+1)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	if !strings.Contains(body, `rate_limiter_requests_total{policy="new-limit",result="shadow_limited"} 2`) {
+		t.Fatalf("expected two shadow denials, got:\n%s", body)
+	}
+	if strings.Contains(body, `result="limited"`) {
+		t.Fatalf("shadow denials must not be counted as enforced rejections:\n%s", body)
+	}
+}
+
+func TestShadowModeRecordsNoBans(t *testing.T) {
+	ctx := context.Background()
+	policy := penaltyPolicy(time.Minute)
+	policy.Mode = "shadow"
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		res, _, _, err := manager.Allow(ctx, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+		if err != nil || !res.Allowed || res.Banned {
+			t.Fatalf("request %d: shadow policy must admit without banning, got %+v err=%v", i+1, res, err)
+		}
+	}
+	bans, err := manager.Bans(ctx)
+	if err != nil {
+		t.Fatalf("bans: %v", err)
+	}
+	if len(bans) != 0 {
+		t.Fatalf("expected no bans from a shadow policy, got %+v", bans)
+	}
+}
+
+func TestParsePolicyModeRejectsUnknown(t *testing.T) {
+	policy := singleRequestPolicy("bad")
+	policy.Mode = "observe"
+	if _, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage()); err == nil {
+		t.Fatal("expected an error for an unknown mode")
+	}
+}
+
+func TestShadowLogHashesTheKey(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	defer slog.SetDefault(previous)
+
+	policy := singleRequestPolicy("new-limit")
+	policy.Mode = "shadow"
+	policy.Identity = config.IdentityConfig{Type: "header", Key: "X-API-Key"}
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	hasher := limiter.NewKeyHasher([]byte("shared"))
+	manager.SetKeyHasher(hasher)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.Header.Set("X-API-Key", "secret-client")
+		_, _, _, _ = manager.Allow(context.Background(), req)
+	}
+
+	if strings.Contains(buf.String(), "secret-client") {
+		t.Fatalf("the raw key must not be logged:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), `"key_hash":"`+hasher.Hash("secret-client")+`"`) {
+		t.Fatalf("expected the keyed hash of the client, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,144 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/admin"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func TestSnapshotRestoresLimiterState(t *testing.T) {
+	ctx := context.Background()
+	policies := []config.Policy{singleRequestPolicy("strict")}
+	path := filepath.Join(t.TempDir(), "snapshot.jsonl")
+
+	before := storage.NewMemoryStorage()
+	manager, err := limiter.NewManagerFromConfig(policies, before)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	if res, _, _, _ := manager.Allow(ctx, req); !res.Allowed {
+		t.Fatal("first request should be allowed")
+	}
+	_ = before.Set(ctx, "sw:removed-policy:client", []byte("stale"), time.Minute)
+	_ = before.Set(ctx, "sw:strict:expiring", []byte("x"), time.Millisecond)
+
+	if _, err := storage.SaveSnapshotFile(before, path); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	after := storage.NewMemoryStorage()
+	restarted, err := limiter.NewManagerFromConfig(policies, after)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	n, err := storage.LoadSnapshotFile(after, path, restarted.OwnsStateKey)
+	if err != nil {
+		t.Fatalf("load snapshot: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected only the live key of a configured policy to be restored, got %d", n)
+	}
+	if res, _, _, _ := restarted.Allow(ctx, req); res.Allowed {
+		t.Fatal("restored state should keep the client limited")
+	}
+}
+
+func TestSnapshotRejectsNewerVersion(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	_, err := store.Import(strings.NewReader(`{"version":99}`+"\n"), nil)
+	if !errors.Is(err, storage.ErrSnapshotVersion) {
+		t.Fatalf("expected version error, got %v", err)
+	}
+}
+
+func TestAdminSnapshotEndpoints(t *testing.T) {
+	ctx := context.Background()
+	source := storage.NewMemoryStorage()
+	_ = source.Set(ctx, "tb:p:a", []byte("state"), time.Minute)
+
+	handler := admin.NewHandler("secret")
+	handler.HandleSnapshots(source, "", 1<<20, nil)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/snapshot", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	snapshot := rec.Body.Bytes()
+
+	target := storage.NewMemoryStorage()
+	importer := admin.NewHandler("secret")
+	importer.HandleSnapshots(target, "", 1<<20, nil)
+	req = httptest.NewRequest(http.MethodPost, "/admin/snapshot", bytes.NewReader(snapshot))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	importer.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import failed: %d %s", rec.Code, rec.Body.String())
+	}
+	if got, err := target.Get(ctx, "tb:p:a"); err != nil || string(got) != "state" {
+		t.Fatalf("expected imported key, got %q err=%v", got, err)
+	}
+}
+
+func TestSnapshotImportIsAllOrNothing(t *testing.T) {
+	ctx := context.Background()
+	source := storage.NewMemoryStorage()
+	for i := 0; i < 3; i++ {
+		_ = source.Set(ctx, fmt.Sprintf("tb:p:%d", i), []byte("state"), time.Minute)
+	}
+	var buf bytes.Buffer
+	if _, err := source.Export(&buf); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	lines := strings.SplitAfter(buf.String(), "\n")
+	corrupt := strings.Join(lines[:3], "") + "{\"key\": truncated"
+
+	target := storage.NewMemoryStorage()
+	handler := admin.NewHandler("secret")
+	handler.HandleSnapshots(target, "", int64(buf.Len()), nil)
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/snapshot", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer secret")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post(corrupt); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "nothing was imported") {
+		t.Fatalf("expected a corrupt snapshot to be rejected, got %d %s", rec.Code, rec.Body)
+	}
+	if rec := post(buf.String() + strings.Repeat(" ", 10)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected an oversized snapshot to be refused, got %d %s", rec.Code, rec.Body)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := target.Get(ctx, fmt.Sprintf("tb:p:%d", i)); !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("expected no key to be applied from rejected snapshots, key %d: %v", i, err)
+		}
+	}
+	if rec := post(buf.String()); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"imported":3`) {
+		t.Fatalf("expected the intact snapshot to import, got %d %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+)
+
+func TestLimiterReadsLegacyJSONState(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	legacy := `{"tokens":0,"last_refill":"` + time.Now().Format(time.RFC3339Nano) + `"}`
+	if err := store.Set(ctx, "legacy:client", []byte(legacy), time.Minute); err != nil {
+		t.Fatalf("set failed: %v", err)
+	}
+
+	tb := limiter.NewTokenBucketLimiter(store, 5, 1, time.Minute, "legacy")
+	res, err := tb.Allow(ctx, "client")
+	if err != nil {
+		t.Fatalf("allow failed: %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("empty bucket loaded from JSON state should deny")
+	}
+
+	data, err := store.Get(ctx, "legacy:client")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if data[0] == '{' {
+		t.Fatal("state should be rewritten in the binary format")
+	}
+}
+
+func TestBinaryStateRoundTrip(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	lb := limiter.NewLeakyBucketLimiter(store, 2, 0.001, "rt")
+	sw := limiter.NewSlidingWindowLimiter(store, 2, time.Minute, "rt-sw")
+
+	for name, l := range map[string]limiter.Limiter{"leaky": lb, "sliding": sw} {
+		for i := 0; i < 2; i++ {
+			if res, err := l.Allow(ctx, "client"); err != nil || !res.Allowed {
+				t.Fatalf("%s request %d should be allowed, err=%v", name, i+1, err)
+			}
+		}
+		if res, err := l.Allow(ctx, "client"); err != nil || res.Allowed {
+			t.Fatalf("%s third request should be denied after round-tripping state, err=%v", name, err)
+		}
+	}
+}
+
+func TestJSONStateEncodingStillWritable(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	tb := limiter.NewTokenBucketLimiter(store, 1, 1, time.Minute, "json")
+	tb.SetStateEncoding(limiter.StateEncodingJSON)
+	if _, err := tb.Allow(ctx, "client"); err != nil {
+		t.Fatalf("allow failed: %v", err)
+	}
+	data, _ := store.Get(ctx, "json:client")
+	if len(data) == 0 || data[0] != '{' {
+		t.Fatalf("expected JSON state, got %q", data)
+	}
+}
+
+func TestStateEncodingIsPerManager(t *testing.T) {
+	ctx := context.Background()
+	policy := singleRequestPolicy("enc")
+	jsonStore, binaryStore := storage.NewMemoryStorage(), storage.NewMemoryStorage()
+
+	jsonManager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, jsonStore, limiter.WithStateEncoding(limiter.StateEncodingJSON))
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	binaryManager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, binaryStore)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	for _, m := range []*limiter.Manager{jsonManager, binaryManager} {
+		if _, _, _, err := m.Allow(ctx, req); err != nil {
+			t.Fatalf("allow failed: %v", err)
+		}
+	}
+
+	key := "sw:enc:192.0.2.1"
+	if data, _ := jsonStore.Get(ctx, key); len(data) == 0 || data[0] != '{' {
+		t.Fatalf("expected JSON state from the json manager, got %q", data)
+	}
+	if data, _ := binaryStore.Get(ctx, key); len(data) == 0 || data[0] == '{' {
+		t.Fatalf("expected binary state from the default manager, got %q", data)
+	}
+}
+
+type resetCounter struct {
+	errorCounter
+	resets map[string]int
+}
+
+func (c *resetCounter) ObserveStateReset(policy string) {
+	c.resets[policy]++
+}
+
+func TestUnreadableStateResetIsObserved(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewMemoryStorage()
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("enc")}, store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	observer := &resetCounter{resets: map[string]int{}}
+	manager.SetErrorObserver(observer)
+
+	// Binary state from a future format version.
+	_ = store.Set(ctx, "sw:enc:192.0.2.1", []byte{0xB5, 99, 3}, time.Minute)
+	res, _, _, err := manager.Allow(ctx, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+	if err != nil || !res.Allowed {
+		t.Fatalf("expected the request to be allowed on fresh state, got %+v err=%v", res, err)
+	}
+	if observer.resets["enc"] != 1 {
+		t.Fatalf("expected one observed state reset, got %v", observer.resets)
+	}
+}
@@ -0,0 +1,441 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
+	"github.com/rohankarn35/rate_limiter_golang/internal/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// testCA issues certificates for TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create ca: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a PEM certificate and key for commonName, valid for 127.0.0.1.
+func (ca *testCA) issue(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	return ca.issueFor(t, commonName, net.ParseIP("127.0.0.1"))
+}
+
+// issueFor returns a PEM certificate and key for commonName, valid for ip.
+func (ca *testCA) issueFor(t *testing.T, commonName string, ip net.IP) (certPEM, keyPEM []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{ip},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("issue certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+func (ca *testCA) clientCert(t *testing.T, commonName string) tls.Certificate {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, commonName)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("client key pair: %v", err)
+	}
+	return cert
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+// serverFiles writes a server certificate issued by ca and ca itself to dir.
+func serverFiles(t *testing.T, dir string, ca *testCA, commonName string) server.TLSConfig {
+	t.Helper()
+	certPEM, keyPEM := ca.issue(t, commonName)
+	cfg := server.TLSConfig{
+		CertFile:     filepath.Join(dir, "tls.crt"),
+		KeyFile:      filepath.Join(dir, "tls.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+		MinVersion:   tls.VersionTLS12,
+	}
+	writeFile(t, cfg.CertFile, certPEM)
+	writeFile(t, cfg.KeyFile, keyPEM)
+	writeFile(t, cfg.ClientCAFile, ca.pem)
+	return cfg
+}
+
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+func startHTTPS(t *testing.T, certs *server.CertReloader) string {
+	t.Helper()
+	return startHTTPSHandler(t, certs, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+}
+
+func startHTTPSHandler(t *testing.T, certs *server.CertReloader, handler http.Handler) string {
+	t.Helper()
+	addr := freeAddress(t)
+	srv := server.NewHTTPServer(server.HTTPConfig{
+		Address: addr,
+		TLS:     certs.ServerConfig("h2", "http/1.1"),
+	}, handler)
+	go func() { _ = srv.Start() }()
+	t.Cleanup(func() { _ = srv.Shutdown(context.Background()) })
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if conn, err := net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			return addr
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("https server did not start on %s", addr)
+	return ""
+}
+
+func httpsGet(addr string, cfg *tls.Config) (*http.Response, error) {
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, ForceAttemptHTTP2: true}, Timeout: 2 * time.Second}
+	return client.Get("https://" + addr + "/")
+}
+
+func TestTLSHTTPRequiresClientCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := serverFiles(t, t.TempDir(), ca, "limiter")
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	certs, err := server.NewCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	addr := startHTTPS(t, certs)
+
+	if _, err := httpsGet(addr, &tls.Config{RootCAs: ca.pool()}); err == nil {
+		t.Fatal("expected the handshake to fail without a client certificate")
+	}
+	other := newTestCA(t)
+	if _, err := httpsGet(addr, &tls.Config{RootCAs: ca.pool(), Certificates: []tls.Certificate{other.clientCert(t, "intruder")}}); err == nil {
+		t.Fatal("expected a certificate from another CA to be rejected")
+	}
+
+	resp, err := httpsGet(addr, &tls.Config{RootCAs: ca.pool(), Certificates: []tls.Certificate{ca.clientCert(t, "billing")}})
+	if err != nil {
+		t.Fatalf("expected mTLS request to succeed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.ProtoMajor != 2 {
+		t.Fatalf("expected 200 over HTTP/2, got %d over %s", resp.StatusCode, resp.Proto)
+	}
+}
+
+func TestTLSEnforcesMinimumVersion(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := serverFiles(t, t.TempDir(), ca, "limiter")
+	cfg.ClientCAFile = ""
+	cfg.MinVersion = tls.VersionTLS13
+	certs, err := server.NewCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	addr := startHTTPS(t, certs)
+
+	if _, err := httpsGet(addr, &tls.Config{RootCAs: ca.pool(), MaxVersion: tls.VersionTLS12}); err == nil {
+		t.Fatal("expected a TLS 1.2 client to be rejected")
+	}
+	resp, err := httpsGet(addr, &tls.Config{RootCAs: ca.pool()})
+	if err != nil {
+		t.Fatalf("expected a TLS 1.3 client to connect: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestTLSReloadsRotatedCertificate(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := serverFiles(t, t.TempDir(), ca, "first")
+	cfg.ClientCAFile = ""
+	certs, err := server.NewCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go certs.Watch(ctx, 10*time.Millisecond)
+	addr := startHTTPS(t, certs)
+
+	servedName := func() string {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{RootCAs: ca.pool()})
+		if err != nil {
+			t.Fatalf("handshake failed: %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+	if got := servedName(); got != "first" {
+		t.Fatalf("expected the initial certificate, got %q", got)
+	}
+
+	// A broken rotation keeps the previous certificate in service.
+	writeFile(t, cfg.CertFile, []byte("not a certificate"))
+	time.Sleep(50 * time.Millisecond)
+	if got := servedName(); got != "first" {
+		t.Fatalf("expected the previous certificate after a bad reload, got %q", got)
+	}
+
+	certPEM, keyPEM := ca.issue(t, "second")
+	writeFile(t, cfg.KeyFile, keyPEM)
+	writeFile(t, cfg.CertFile, certPEM)
+	deadline := time.Now().Add(2 * time.Second)
+	for servedName() != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("rotated certificate was not picked up")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTLSGRPCListener(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := serverFiles(t, t.TempDir(), ca, "limiter")
+	cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	certs, err := server.NewCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(certs.ServerConfig("h2"))))
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	addr := freeAddress(t)
+	grpcServer := server.NewGRPCServer(addr, s)
+	go func() { _ = grpcServer.Start() }()
+	defer grpcServer.Stop(context.Background())
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		RootCAs:      ca.pool(),
+		Certificates: []tls.Certificate{ca.clientCert(t, "billing")},
+	})))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("health check over mTLS failed: %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("expected SERVING, got %v", resp.Status)
+	}
+}
+
+func TestTLSConfigValidation(t *testing.T) {
+	if _, err := server.ParseTLSVersion("1.1"); err == nil {
+		t.Fatal("expected TLS 1.1 to be rejected")
+	}
+	if auth, err := server.ParseClientAuth("", true); err != nil || auth != tls.VerifyClientCertIfGiven {
+		t.Fatalf("expected a client CA to verify client certificates if given, got %v %v", auth, err)
+	}
+	if _, err := server.NewCertReloader(server.TLSConfig{CertFile: "missing.crt", KeyFile: "missing.key"}); err == nil {
+		t.Fatal("expected missing files to be rejected")
+	}
+	if _, err := server.NewCertReloader(server.TLSConfig{CertFile: "a", KeyFile: "b", ClientAuth: tls.RequireAndVerifyClientCert}); err == nil {
+		t.Fatal("expected verification without a client CA to be rejected")
+	}
+}
+
+func TestTLSDefaultClientAuthLeavesProbesOpen(t *testing.T) {
+	ca := newTestCA(t)
+	cfg := serverFiles(t, t.TempDir(), ca, "limiter")
+	auth, err := server.ParseClientAuth("", true)
+	if err != nil {
+		t.Fatalf("client auth: %v", err)
+	}
+	cfg.ClientAuth = auth
+	certs, err := server.NewCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", ok)
+	mux.Handle("/api/", middleware.RequireClientCert(ok))
+	addr := startHTTPSHandler(t, certs, mux)
+
+	get := func(path string, clientCerts ...tls.Certificate) int {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.pool(), Certificates: clientCerts}}, Timeout: 2 * time.Second}
+		resp, err := client.Get("https://" + addr + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := get("/healthz"); code != http.StatusOK {
+		t.Fatalf("expected probes without a certificate to pass, got %d", code)
+	}
+	if code := get("/api/v1/payments"); code != http.StatusForbidden {
+		t.Fatalf("expected the API to require a client certificate, got %d", code)
+	}
+	if code := get("/api/v1/payments", ca.clientCert(t, "billing")); code != http.StatusOK {
+		t.Fatalf("expected the API to accept a verified certificate, got %d", code)
+	}
+	other := newTestCA(t)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: ca.pool(), Certificates: []tls.Certificate{other.clientCert(t, "intruder")}}}, Timeout: 2 * time.Second}
+	if _, err := client.Get("https://" + addr + "/healthz"); err == nil {
+		t.Fatal("expected a certificate from another CA to fail the handshake")
+	}
+}
+
+func TestGRPCRequireClientCertSkipsHealth(t *testing.T) {
+	interceptor := middleware.UnaryRequireClientCertInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	anonymous := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{}})
+	verified := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{}}}},
+	}})
+
+	if _, err := interceptor(anonymous, nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, handler); err != nil {
+		t.Fatalf("expected health checks without a certificate to pass: %v", err)
+	}
+	if _, err := interceptor(anonymous, nil, &grpc.UnaryServerInfo{FullMethod: "/payments.Service/Charge"}, handler); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without a certificate, got %v", err)
+	}
+	if _, err := interceptor(verified, nil, &grpc.UnaryServerInfo{FullMethod: "/payments.Service/Charge"}, handler); err != nil {
+		t.Fatalf("expected a verified peer to pass: %v", err)
+	}
+}
+
+func TestPeerTLSRejectsAPIClientsAndUnknownHosts(t *testing.T) {
+	peerCA, apiCA := newTestCA(t), newTestCA(t)
+	dir := t.TempDir()
+	cfg := serverFiles(t, dir, peerCA, "node-0")
+	cfg.PeerCAFile = filepath.Join(dir, "peer-ca.crt")
+	writeFile(t, cfg.PeerCAFile, peerCA.pem)
+	writeFile(t, cfg.ClientCAFile, apiCA.pem)
+	certs, err := server.NewCertReloader(cfg)
+	if err != nil {
+		t.Fatalf("failed to load certificates: %v", err)
+	}
+	if _, _, err := certs.PeerConfigs(nil); err == nil {
+		t.Fatal("expected peer tls without members to be refused")
+	}
+	serverTLS, _, err := certs.PeerConfigs([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("peer configs: %v", err)
+	}
+
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", serverTLS)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer lis.Close()
+	handshakes := make(chan error)
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			handshakes <- conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	handshake := func(cert tls.Certificate) error {
+		conn, err := tls.Dial("tcp", lis.Addr().String(), &tls.Config{RootCAs: peerCA.pool(), Certificates: []tls.Certificate{cert}, NextProtos: []string{"h2"}})
+		if err == nil {
+			defer conn.Close()
+		}
+		return <-handshakes
+	}
+
+	if err := handshake(apiCA.clientCert(t, "billing")); err == nil {
+		t.Fatal("expected an API client certificate to be refused as a peer")
+	}
+	certPEM, keyPEM := peerCA.issueFor(t, "stranger", net.ParseIP("10.9.9.9"))
+	stranger, _ := tls.X509KeyPair(certPEM, keyPEM)
+	if err := handshake(stranger); err == nil {
+		t.Fatal("expected a peer certificate for a host outside the cluster to be refused")
+	}
+	if err := handshake(peerCA.clientCert(t, "node-1")); err != nil {
+		t.Fatalf("expected a cluster member to be accepted: %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rohankarn35/rate_limiter_golang/internal/api/middleware"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/config"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/limiter"
+	"github.com/rohankarn35/rate_limiter_golang/pkg/storage"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// recordSpans installs an in-memory span recorder as the global tracer
+// provider for the duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func spansByName(recorder *tracetest.SpanRecorder) map[string][]sdktrace.ReadOnlySpan {
+	spans := map[string][]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	return spans
+}
+
+func spanAttr(span sdktrace.ReadOnlySpan, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func spanContextTraceID(ctx context.Context) string {
+	return trace.SpanContextFromContext(ctx).TraceID().String()
+}
+
+func tracedManager(t *testing.T) *limiter.Manager {
+	t.Helper()
+	store := storage.NewInstrumentedStorage(storage.NewMemoryStorage(), "memory", nil)
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{singleRequestPolicy("api")}, store)
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+	return manager
+}
+
+func TestTracingHTTPMiddlewareJoinsIncomingTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	manager := tracedManager(t)
+
+	var handlerTraceID string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerTraceID = spanContextTraceID(r.Context())
+	})
+	handler := middleware.RateLimiter(manager, nil)(next)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+		req.Header.Set("traceparent", testTraceParent)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	spans := spansByName(recorder)
+	httpSpans := spans["ratelimit.http"]
+	if len(httpSpans) != 2 {
+		t.Fatalf("expected 2 ratelimit.http spans, got %d", len(httpSpans))
+	}
+	if got := httpSpans[0].SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("span did not join the incoming trace: %s", got)
+	}
+	if got := httpSpans[0].Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Fatalf("expected the caller's span as parent, got %s", got)
+	}
+	if handlerTraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("trace context not passed to the next handler: %q", handlerTraceID)
+	}
+
+	if v, _ := spanAttr(httpSpans[0], "ratelimit.policy"); v.AsString() != "api" {
+		t.Fatalf("expected policy attribute, got %q", v.AsString())
+	}
+	if v, _ := spanAttr(httpSpans[0], "ratelimit.allowed"); !v.AsBool() {
+		t.Fatal("first request should be recorded as allowed")
+	}
+	if v, _ := spanAttr(httpSpans[1], "ratelimit.allowed"); v.AsBool() {
+		t.Fatal("second request should be recorded as limited")
+	}
+	if v, ok := spanAttr(httpSpans[1], "ratelimit.remaining"); !ok || v.AsInt64() != 0 {
+		t.Fatalf("expected remaining 0, got %v", v.Emit())
+	}
+
+	decide := spans["ratelimit.decide"]
+	if len(decide) != 2 || decide[0].Parent().SpanID() != httpSpans[0].SpanContext().SpanID() {
+		t.Fatalf("expected a decision span under each request span, got %d", len(decide))
+	}
+	gets := spans["storage.get"]
+	if len(gets) == 0 {
+		t.Fatal("expected storage spans")
+	}
+	if v, _ := spanAttr(gets[0], "storage.driver"); v.AsString() != "memory" {
+		t.Fatalf("expected storage.driver attribute, got %q", v.AsString())
+	}
+	if _, ok := spanAttr(gets[0], "storage.latency_ms"); !ok {
+		t.Fatal("expected storage.latency_ms attribute")
+	}
+	if gets[0].Parent().SpanID() != decide[0].SpanContext().SpanID() {
+		t.Fatal("storage span should be a child of the decision span")
+	}
+}
+
+func TestTracingGRPCInterceptorJoinsIncomingTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	policy := singleRequestPolicy("rpc")
+	policy.Routes = []string{"/demo.Service/*"}
+	policy.Identity = config.IdentityConfig{Type: "header", Key: "X-Client"}
+	manager, err := limiter.NewManagerFromConfig([]config.Policy{policy}, storage.NewMemoryStorage())
+	if err != nil {
+		t.Fatalf("failed to build manager: %v", err)
+	}
+
+	interceptor := middleware.UnaryRateLimitInterceptor(manager, nil)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("traceparent", testTraceParent, "x-client", "alice"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/demo.Service/Call"}
+	if _, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	spans := spansByName(recorder)["ratelimit.grpc"]
+	if len(spans) != 1 {
+		t.Fatalf("expected one ratelimit.grpc span, got %d", len(spans))
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("span did not join the incoming trace: %s", got)
+	}
+	if v, _ := spanAttr(spans[0], "ratelimit.policy"); v.AsString() != "rpc" {
+		t.Fatalf("expected policy attribute, got %q", v.AsString())
+	}
+	if v, _ := spanAttr(spans[0], "ratelimit.allowed"); !v.AsBool() {
+		t.Fatal("request should be recorded as allowed")
+	}
+}